@@ -11,8 +11,6 @@ import (
 )
 
 func setupApp(ctx context.Context) *ucli.App {
-	cli.Init(ctx)
-
 	app := ucli.NewApp()
 	app.Name = "Passline"
 	app.Usage = "Password manager"
@@ -28,23 +26,81 @@ WEBSITE:
 
 	`, ucli.AppHelpTemplate)
 
+	app.Flags = []ucli.Flag{
+		&ucli.StringFlag{
+			Name:    "vault",
+			EnvVars: []string{"PASSLINE_VAULT"},
+			Usage:   "Use another vault than the default one",
+		},
+	}
+
+	app.Before = func(c *ucli.Context) error { return cli.Init(ctx, c) }
+
 	// default command to get password
 	app.Action = func(c *ucli.Context) error { return cli.DisplayItem(ctx, c) }
 
 	app.Commands = []*ucli.Command{
+		{
+			Name:  "age",
+			Usage: "Encrypt the vault key with age instead of the global password",
+			Subcommands: []*ucli.Command{
+				{
+					Name:   "identity",
+					Usage:  "Create an age identity file and show its recipient",
+					Action: func(c *ucli.Context) error { return cli.CreateAgeIdentity(ctx, c) },
+				},
+				{
+					Name:   "init",
+					Usage:  "Create the vault key of an empty age vault",
+					Action: func(c *ucli.Context) error { return cli.InitAge(ctx, c) },
+				},
+				{
+					Name:   "update",
+					Usage:  "Encrypt the vault key to the configured recipients",
+					Action: func(c *ucli.Context) error { return cli.UpdateAgeRecipients(ctx, c) },
+				},
+			},
+		},
 		{
 			Name:      "backup",
 			Aliases:   []string{"b"},
 			Usage:     "Create a backup",
 			ArgsUsage: "<path>",
 			Action:    func(c *ucli.Context) error { return cli.CreateBackup(ctx, c) },
+			Subcommands: []*ucli.Command{
+				{
+					Name:    "list",
+					Aliases: []string{"ls"},
+					Usage:   "List automatic backups",
+					Action:  func(c *ucli.Context) error { return cli.ListBackups(ctx, c) },
+				},
+			},
 		},
 		{
 			Name:      "add",
 			Aliases:   []string{"a"},
 			Usage:     "Add an existing password for a website",
 			ArgsUsage: "<name> <username> <password>",
-			Action:    func(c *ucli.Context) error { return cli.AddItem(ctx, c) },
+			Flags: []ucli.Flag{
+				&ucli.StringSliceFlag{
+					Name:  "url",
+					Usage: "Url the item is used for as [domain|host|exact|regex:]url, can be repeated",
+				},
+				&ucli.StringFlag{
+					Name:  "folder",
+					Usage: "Folder of the item, sub folders are separated by slashes",
+				},
+				&ucli.StringSliceFlag{
+					Name:    "tag",
+					Aliases: []string{"t"},
+					Usage:   "Tag of the item, can be repeated",
+				},
+				&ucli.BoolFlag{
+					Name:  "refuse-breached",
+					Usage: "Refuse a password found in the breach dataset",
+				},
+			},
+			Action: func(c *ucli.Context) error { return cli.AddItem(ctx, c) },
 		},
 		{
 			Name:      "delete",
@@ -60,6 +116,46 @@ WEBSITE:
 			ArgsUsage: "<name> <username>",
 			Action:    func(c *ucli.Context) error { return cli.EditItem(ctx, c) },
 		},
+		{
+			Name:      "export",
+			Usage:     "Export decrypted items to another format",
+			ArgsUsage: "<path>",
+			Flags: []ucli.Flag{
+				&ucli.StringFlag{
+					Name:    "format",
+					Aliases: []string{"f"},
+					Value:   "csv",
+					Usage:   "Export format (bitwarden, keepass-csv, keepass-xml, csv)",
+				},
+				&ucli.StringFlag{
+					Name:  "item",
+					Usage: "Export only the item with this name",
+				},
+				&ucli.StringFlag{
+					Name:  "filter",
+					Usage: "Export only items whose name contains the filter",
+				},
+			},
+			Action: func(c *ucli.Context) error { return cli.ExportItems(ctx, c) },
+		},
+		{
+			Name:  "firestore",
+			Usage: "Manage the vaults of the firestore storage",
+			Subcommands: []*ucli.Command{
+				{
+					Name:    "vaults",
+					Aliases: []string{"ls"},
+					Usage:   "List the vaults of the firestore project",
+					Action:  func(c *ucli.Context) error { return cli.ListFirestoreVaults(ctx, c) },
+				},
+				{
+					Name:      "use",
+					Usage:     "Switch to another vault, it is created with the first item",
+					ArgsUsage: "<vault>",
+					Action:    func(c *ucli.Context) error { return cli.UseFirestoreVault(ctx, c) },
+				},
+			},
+		},
 		{
 			Name:      "generate",
 			Aliases:   []string{"g"},
@@ -72,15 +168,71 @@ WEBSITE:
 					Value:   "default",
 					Usage:   "Change between default and advanced mode",
 				},
+				&ucli.StringSliceFlag{
+					Name:  "url",
+					Usage: "Url the item is used for as [domain|host|exact|regex:]url, can be repeated",
+				},
+				&ucli.StringFlag{
+					Name:  "folder",
+					Usage: "Folder of the item, sub folders are separated by slashes",
+				},
+				&ucli.StringSliceFlag{
+					Name:    "tag",
+					Aliases: []string{"t"},
+					Usage:   "Tag of the item, can be repeated",
+				},
 			},
 			Action: func(c *ucli.Context) error { return cli.GenerateItem(ctx, c) },
 		},
+		{
+			Name:      "import",
+			Aliases:   []string{"i"},
+			Usage:     "Import items from another password manager",
+			ArgsUsage: "<path>",
+			Flags: []ucli.Flag{
+				&ucli.StringFlag{
+					Name:    "format",
+					Aliases: []string{"f"},
+					Usage:   "Import format (keepass-xml, keepass-csv, bitwarden, 1password, lastpass, chrome, firefox, pass)",
+				},
+			},
+			Action: func(c *ucli.Context) error { return cli.ImportItems(ctx, c) },
+		},
+		{
+			Name:  "keyfile",
+			Usage: "Require a key file in addition to the global password",
+			Subcommands: []*ucli.Command{
+				{
+					Name:      "generate",
+					Usage:     "Create a new key file",
+					ArgsUsage: "<path>",
+					Action:    func(c *ucli.Context) error { return cli.GenerateKeyFile(ctx, c) },
+				},
+				{
+					Name:      "set",
+					Usage:     "Encrypt the items of the vault with the key file, without a path it is removed",
+					ArgsUsage: "[path]",
+					Action:    func(c *ucli.Context) error { return cli.ChangeKeyFile(ctx, c) },
+				},
+			},
+		},
 		{
 			Name:      "list",
 			Aliases:   []string{"ls"},
 			Usage:     "List all items",
 			ArgsUsage: "<name>",
-			Action:    func(c *ucli.Context) error { return cli.ListItems(ctx, c) },
+			Flags: []ucli.Flag{
+				&ucli.StringFlag{
+					Name:  "folder",
+					Usage: "List only items in the folder and its sub folders",
+				},
+				&ucli.StringSliceFlag{
+					Name:    "tag",
+					Aliases: []string{"t"},
+					Usage:   "List only items with the tag, can be repeated",
+				},
+			},
+			Action: func(c *ucli.Context) error { return cli.ListItems(ctx, c) },
 		},
 		{
 			Name:      "restore",
@@ -89,6 +241,204 @@ WEBSITE:
 			ArgsUsage: "<path>",
 			Action:    func(c *ucli.Context) error { return cli.RestoreBackup(ctx, c) },
 		},
+		{
+			Name:      "search",
+			Aliases:   []string{"s"},
+			Usage:     "Search items by name, url, username, tag and folder",
+			ArgsUsage: "<query>",
+			Action:    func(c *ucli.Context) error { return cli.SearchItems(ctx, c) },
+		},
+		{
+			Name:      "find-url",
+			Usage:     "List the credentials used for an url",
+			ArgsUsage: "<url>",
+			Action:    func(c *ucli.Context) error { return cli.FindURL(ctx, c) },
+		},
+		{
+			Name:  "audit",
+			Usage: "Report weak, reused and old passwords",
+			Flags: []ucli.Flag{
+				&ucli.IntFlag{
+					Name:  "max-age",
+					Usage: "Report passwords not changed for more `DAYS`, 0 disables the check",
+					Value: 365,
+				},
+				&ucli.BoolFlag{
+					Name:  "json",
+					Usage: "Print the report as json",
+				},
+			},
+			Action: func(c *ucli.Context) error { return cli.Audit(ctx, c) },
+		},
+		{
+			Name:   "tui",
+			Usage:  "Browse the items in a full screen interface",
+			Action: func(c *ucli.Context) error { return cli.TUI(ctx, c) },
+		},
+		{
+			Name:  "migrate",
+			Usage: "Copy all items to another storage and switch to it",
+			Flags: []ucli.Flag{
+				&ucli.StringFlag{
+					Name:  "from",
+					Usage: "Storage to copy from, defaults to the configured storage",
+				},
+				&ucli.StringFlag{
+					Name:     "to",
+					Usage:    "Storage to copy to",
+					Required: true,
+				},
+			},
+			Action: func(c *ucli.Context) error { return cli.Migrate(ctx, c) },
+		},
+		{
+			Name:  "serve",
+			Usage: "Run a sync server for the remote storage",
+			Flags: []ucli.Flag{
+				&ucli.StringFlag{
+					Name:    "address",
+					Aliases: []string{"a"},
+					Usage:   "Address to listen on",
+				},
+			},
+			Action: func(c *ucli.Context) error { return cli.Serve(ctx, c) },
+			Subcommands: []*ucli.Command{
+				{
+					Name:  "device",
+					Usage: "Manage the devices with access to the server",
+					Subcommands: []*ucli.Command{
+						{
+							Name:      "add",
+							Usage:     "Add a device and print its token",
+							ArgsUsage: "<name>",
+							Action:    func(c *ucli.Context) error { return cli.AddDevice(ctx, c) },
+						},
+						{
+							Name:    "list",
+							Aliases: []string{"ls"},
+							Usage:   "List all devices",
+							Action:  func(c *ucli.Context) error { return cli.ListDevices(ctx, c) },
+						},
+						{
+							Name:      "remove",
+							Aliases:   []string{"rm"},
+							Usage:     "Revoke the token of a device",
+							ArgsUsage: "<name>",
+							Action:    func(c *ucli.Context) error { return cli.RemoveDevice(ctx, c) },
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:  "storage",
+			Usage: "Show the available storages",
+			Subcommands: []*ucli.Command{
+				{
+					Name:    "list",
+					Aliases: []string{"ls"},
+					Usage:   "List all storages and their settings",
+					Action:  func(c *ucli.Context) error { return cli.ListStorages(ctx, c) },
+				},
+			},
+		},
+		{
+			Name:  "team",
+			Usage: "Share a vault with the public keys of its members",
+			Subcommands: []*ucli.Command{
+				{
+					Name:   "identity",
+					Usage:  "Create the key pair of the user and show the public key",
+					Action: func(c *ucli.Context) error { return cli.CreateIdentity(ctx, c) },
+				},
+				{
+					Name:      "init",
+					Usage:     "Create the vault key with yourself as first member",
+					ArgsUsage: "<name>",
+					Action:    func(c *ucli.Context) error { return cli.InitTeam(ctx, c) },
+				},
+				{
+					Name:    "list",
+					Aliases: []string{"ls"},
+					Usage:   "List the members of the team vault",
+					Action:  func(c *ucli.Context) error { return cli.ListMembers(ctx, c) },
+				},
+				{
+					Name:      "add",
+					Usage:     "Add a member by its public key",
+					ArgsUsage: "<name> <public key>",
+					Action:    func(c *ucli.Context) error { return cli.AddMember(ctx, c) },
+				},
+				{
+					Name:      "remove",
+					Aliases:   []string{"rm"},
+					Usage:     "Remove a member and replace the vault key",
+					ArgsUsage: "<name>",
+					Action:    func(c *ucli.Context) error { return cli.RemoveMember(ctx, c) },
+				},
+			},
+		},
+		{
+			Name:  "vault",
+			Usage: "Manage vaults with their own storage and master password",
+			Subcommands: []*ucli.Command{
+				{
+					Name:    "list",
+					Aliases: []string{"ls"},
+					Usage:   "List all vaults",
+					Action:  func(c *ucli.Context) error { return cli.ListVaults(ctx, c) },
+				},
+				{
+					Name:      "add",
+					Usage:     "Add a vault",
+					ArgsUsage: "<name>",
+					Flags: []ucli.Flag{
+						&ucli.StringFlag{
+							Name:  "storage",
+							Value: "local",
+							Usage: "Storage of the vault",
+						},
+						&ucli.StringFlag{
+							Name:  "encryption",
+							Usage: "Encryption of the vault, password, team or age",
+						},
+						&ucli.StringFlag{
+							Name:  "directory",
+							Usage: "Directory of the vault, defaults to vaults/<name> in the passline directory",
+						},
+					},
+					Action: func(c *ucli.Context) error { return cli.AddVault(ctx, c) },
+				},
+				{
+					Name:      "remove",
+					Aliases:   []string{"rm"},
+					Usage:     "Remove a vault from the config, its data is kept",
+					ArgsUsage: "<name>",
+					Action:    func(c *ucli.Context) error { return cli.RemoveVault(ctx, c) },
+				},
+				{
+					Name:      "default",
+					Usage:     "Use a vault by default",
+					ArgsUsage: "<name>",
+					Action:    func(c *ucli.Context) error { return cli.DefaultVault(ctx, c) },
+				},
+			},
+		},
+		{
+			Name:  "sync",
+			Usage: "Synchronize the storage with its remote",
+			Flags: []ucli.Flag{
+				&ucli.BoolFlag{
+					Name:  "keep-local",
+					Usage: "Resolve conflicts by writing the local versions",
+				},
+				&ucli.BoolFlag{
+					Name:  "keep-remote",
+					Usage: "Resolve conflicts by discarding the local versions",
+				},
+			},
+			Action: func(c *ucli.Context) error { return cli.Sync(ctx, c) },
+		},
 	}
 
 	sort.Sort(ucli.FlagsByName(app.Flags))
@@ -5,25 +5,19 @@ go 1.13
 require (
 	cloud.google.com/go/firestore v1.0.0
 	cloud.google.com/go/storage v1.1.1 // indirect
-	firebase.google.com/go v3.9.0+incompatible
+	filippo.io/age v1.0.0
 	github.com/atotto/clipboard v0.1.2
 	github.com/eiannone/keyboard v0.0.0-20190314115158-7169d0afeb4f
 	github.com/fatih/color v1.7.0
-	github.com/gbin/goncurses v0.0.0-20141106211150-34d9399fcea5
 	github.com/k0kubun/go-ansi v0.0.0-20180517002512-3bf9e2903213
 	github.com/mattn/go-colorable v0.1.4 // indirect
-	github.com/mattn/go-isatty v0.0.10 // indirect
 	github.com/mattn/go-runewidth v0.0.6 // indirect
 	github.com/nsf/termbox-go v0.0.0-20190817171036-93860e161317
-	github.com/pborman/ansi v0.0.0-20160920233902-86f499584b0a
-	github.com/pkg/term v0.0.0-20190109203006-aa71e9d9e942
-	github.com/rthornton128/goncurses v0.0.0-20190619232326-514c399d6652
-	github.com/urfave/cli v1.22.1
 	github.com/urfave/cli/v2 v2.0.0
-	github.com/zetamatta/go-getch v0.0.0-20181223074817-31a2298565de
-	golang.org/x/crypto v0.0.0-20191011191535-87dc89f01550
-	golang.org/x/net v0.0.0-20190620200207-3b0461eec859
-	golang.org/x/sys v0.0.0-20191008105621-543471e840be
+	golang.org/x/crypto v0.0.0-20210817164053-32db794688a5
+	golang.org/x/net v0.0.0-20210226172049-e18ecbb05110
 	google.golang.org/api v0.11.0
 	google.golang.org/appengine v1.6.5 // indirect
+	google.golang.org/grpc v1.21.1
+	modernc.org/sqlite v1.20.4
 )
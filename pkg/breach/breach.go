@@ -0,0 +1,215 @@
+// Package breach looks up passwords in the Have I Been Pwned password dataset
+// without sending them anywhere. Only the first 5 characters of the SHA-1 hash
+// of a password are used to select the range of hashes it is searched in.
+package breach
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// prefixLength is the number of hash characters a range is selected by
+const prefixLength = 5
+
+// cacheDuration is how long a cached result is used
+const cacheDuration = 30 * 24 * time.Hour
+
+// ErrNotConfigured is returned if neither a dataset nor a server is set
+var ErrNotConfigured = errors.New("No breach dataset configured")
+
+// Checker counts how often a password appears in the dataset. The ranges are
+// read from files named by the hash prefix, like 21BD1 or 21BD1.txt as
+// written by the PwnedPasswordsDownloader, or requested from a server with
+// the range api at <url>/range/<prefix>.
+type Checker struct {
+	directory string
+	url       string
+	client    *http.Client
+
+	cache     map[string]cacheEntry
+	cacheFile string
+	cacheKey  []byte
+	changed   bool
+}
+
+type cacheEntry struct {
+	Count   int       `json:"count"`
+	Checked time.Time `json:"checked"`
+}
+
+// New returns a checker for the range files in directory or, if directory
+// is empty, the server at url
+func New(directory, url string) (*Checker, error) {
+	if directory == "" && url == "" {
+		return nil, ErrNotConfigured
+	}
+
+	if directory != "" {
+		info, err := os.Stat(directory)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			return nil, errors.New("Breach dataset is not a directory: " + directory)
+		}
+	}
+
+	return &Checker{
+		directory: directory,
+		url:       strings.TrimSuffix(url, "/"),
+		client:    &http.Client{Timeout: 30 * time.Second},
+		cache:     map[string]cacheEntry{},
+	}, nil
+}
+
+// UseCache keeps the results in file. The hashes are stored as HMAC with
+// key, so the file does not reveal the passwords.
+func (c *Checker) UseCache(file string, key []byte) error {
+	c.cacheFile = file
+	c.cacheKey = key
+
+	data, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, &c.cache)
+}
+
+// Count returns how often the password appears in the dataset, 0 if it
+// is not known to be breached
+func (c *Checker) Count(password string) (int, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	id := c.cacheID(hash)
+	if entry, ok := c.cache[id]; ok && time.Since(entry.Checked) < cacheDuration {
+		return entry.Count, nil
+	}
+
+	count, err := c.lookup(hash[:prefixLength], hash[prefixLength:])
+	if err != nil {
+		return 0, err
+	}
+
+	if c.cacheFile != "" {
+		c.cache[id] = cacheEntry{Count: count, Checked: time.Now().UTC()}
+		c.changed = true
+	}
+
+	return count, nil
+}
+
+// Save writes the cache if results were added
+func (c *Checker) Save() error {
+	if c.cacheFile == "" || !c.changed {
+		return nil
+	}
+
+	// Drop expired results
+	for id, entry := range c.cache {
+		if time.Since(entry.Checked) >= cacheDuration {
+			delete(c.cache, id)
+		}
+	}
+
+	data, err := json.Marshal(c.cache)
+	if err != nil {
+		return err
+	}
+
+	err = ioutil.WriteFile(c.cacheFile, data, 0600)
+	if err != nil {
+		return err
+	}
+
+	c.changed = false
+	return nil
+}
+
+func (c *Checker) cacheID(hash string) string {
+	mac := hmac.New(sha256.New, c.cacheKey)
+	mac.Write([]byte(hash))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// lookup searches the range of prefix for suffix
+func (c *Checker) lookup(prefix, suffix string) (int, error) {
+	if c.directory != "" {
+		return c.lookupFile(prefix, suffix)
+	}
+
+	return c.lookupServer(prefix, suffix)
+}
+
+func (c *Checker) lookupFile(prefix, suffix string) (int, error) {
+	for _, name := range []string{prefix + ".txt", prefix, strings.ToLower(prefix) + ".txt", strings.ToLower(prefix)} {
+		file, err := os.Open(filepath.Join(c.directory, name))
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return 0, err
+		}
+		defer file.Close()
+
+		return find(file, suffix)
+	}
+
+	return 0, errors.New("Missing range file " + prefix + " in " + c.directory)
+}
+
+func (c *Checker) lookupServer(prefix, suffix string) (int, error) {
+	req, err := http.NewRequest(http.MethodGet, c.url+"/range/"+prefix, nil)
+	if err != nil {
+		return 0, err
+	}
+	// Padding hides the size of the response
+	req.Header.Set("Add-Padding", "true")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, errors.New("Breach server responded with " + resp.Status + " for range " + prefix)
+	}
+
+	return find(resp.Body, suffix)
+}
+
+// find reads a range of SUFFIX:COUNT lines and returns the count of suffix
+func find(r io.Reader, suffix string) (int, error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		i := strings.IndexByte(line, ':')
+		if i < 0 || !strings.EqualFold(line[:i], suffix) {
+			continue
+		}
+
+		count, err := strconv.Atoi(line[i+1:])
+		if err != nil {
+			return 0, errors.New("Invalid range line: " + line)
+		}
+		return count, nil
+	}
+
+	return 0, scanner.Err()
+}
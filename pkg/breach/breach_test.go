@@ -0,0 +1,96 @@
+package breach
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// SHA-1 of "password" is 5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8
+const passwordRange = "003D68EB55068C33ACE09247EE4C639306B:3\r\n1E4C9B93F3F0682250B6CF8331B7EE68FD8:9659365\r\n"
+
+func TestCheckerDirectory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "passline-breach")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	err = ioutil.WriteFile(filepath.Join(dir, "5BAA6.txt"), []byte(passwordRange), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checker, err := New(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cacheFile := filepath.Join(dir, "cache.json")
+	err = checker.UseCache(cacheFile, []byte("key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if count, err := checker.Count("password"); err != nil || count != 9659365 {
+		t.Errorf("Count(password) = %d, %v; wanted 9659365", count, err)
+	}
+
+	// Range file of 605AD does not exist
+	if _, err := checker.Count("u4WXM56bZatFTS4-quqhlY"); err == nil {
+		t.Errorf("Count() with missing range file succeeded")
+	}
+
+	err = checker.Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The cached result is used without the range file
+	err = os.Remove(filepath.Join(dir, "5BAA6.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checker, _ = New(dir, "")
+	_ = checker.UseCache(cacheFile, []byte("key"))
+	if count, err := checker.Count("password"); err != nil || count != 9659365 {
+		t.Errorf("Count(password) from cache = %d, %v; wanted 9659365", count, err)
+	}
+}
+
+func TestCheckerServer(t *testing.T) {
+	requested := ""
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL.Path
+		if r.URL.Path == "/range/5BAA6" {
+			_, _ = w.Write([]byte(passwordRange))
+		}
+	}))
+	defer server.Close()
+
+	checker, err := New("", server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if count, err := checker.Count("password"); err != nil || count != 9659365 {
+		t.Errorf("Count(password) = %d, %v; wanted 9659365", count, err)
+	}
+
+	if count, err := checker.Count("u4WXM56bZatFTS4-quqhlY"); err != nil || count != 0 {
+		t.Errorf("Count() of an unknown password = %d, %v; wanted 0", count, err)
+	}
+
+	// Only the prefix of the hash is sent
+	if len(requested) != len("/range/")+prefixLength {
+		t.Errorf("Requested %s; wanted a 5 character prefix", requested)
+	}
+
+	if _, err := New("", ""); err != ErrNotConfigured {
+		t.Errorf("New() without dataset = %v; wanted ErrNotConfigured", err)
+	}
+}
@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"context"
+
+	ucli "github.com/urfave/cli/v2"
+
+	"github.com/perryrh0dan/passline/pkg/renderer"
+	"github.com/perryrh0dan/passline/pkg/storage"
+)
+
+func CreateAgeIdentity(ctx context.Context, c *ucli.Context) error {
+	if err := load(ctx); err != nil {
+		return err
+	}
+
+	recipient, err := passline.CreateAgeIdentity()
+	if err != nil {
+		return err
+	}
+
+	renderer.DisplayAgeRecipient(recipient)
+	return nil
+}
+
+func InitAge(ctx context.Context, c *ucli.Context) error {
+	if err := load(ctx); err != nil {
+		return err
+	}
+
+	// Without recipients the global password is the age passphrase
+	var password []byte
+	if passline.PasswordRequired() {
+		password = storage.PasswordPrompt()
+	}
+
+	err := passline.InitAge(ctx, password)
+	if err != nil {
+		return err
+	}
+
+	renderer.SuccessfulInitializedAge()
+	return nil
+}
+
+func UpdateAgeRecipients(ctx context.Context, c *ucli.Context) error {
+	if err := load(ctx); err != nil {
+		return err
+	}
+
+	vaultKey, err := passline.UnlockKey(ctx, nil)
+	if err != nil {
+		// The vault key is still encrypted with a passphrase
+		password := storage.PasswordPrompt()
+
+		vaultKey, err = passline.UnlockKey(ctx, password)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = passline.UpdateAgeRecipients(ctx, vaultKey)
+	if err != nil {
+		return err
+	}
+
+	renderer.SuccessfulUpdatedAgeRecipients()
+	return nil
+}
@@ -2,32 +2,69 @@ package cli
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/atotto/clipboard"
 	ucli "github.com/urfave/cli/v2"
 
+	"github.com/perryrh0dan/passline/pkg/config"
 	"github.com/perryrh0dan/passline/pkg/core"
+	"github.com/perryrh0dan/passline/pkg/exporter"
+	"github.com/perryrh0dan/passline/pkg/importer"
 	"github.com/perryrh0dan/passline/pkg/renderer"
 	"github.com/perryrh0dan/passline/pkg/storage"
+	"github.com/perryrh0dan/passline/pkg/tui"
 	"github.com/perryrh0dan/passline/pkg/util"
 )
 
+// passline is the core of the selected vault, it is created by load
 var passline *core.Core
 
-func Init(ctx context.Context) {
+func Init(ctx context.Context, c *ucli.Context) error {
+	config.SelectVault(c.String("vault"))
+
+	// The global password is asked once per run, backends that need it to
+	// open the storage share it with the commands
+	var password []byte
+	storage.PasswordPrompt = func() []byte {
+		if password == nil {
+			password = getPassword("Enter Global Password: ")
+			println()
+		}
+		return password
+	}
+
+	return nil
+}
+
+// load creates the core of the selected vault. It is only called by the
+// commands that use it, so a broken vault can still be changed or removed.
+func load(ctx context.Context) error {
+	if passline != nil {
+		return nil
+	}
+
 	var err error
 	passline, err = core.NewCore(ctx)
 	if err != nil {
-		renderer.CoreInstanceError()
-		os.Exit(1)
+		return errors.New("Unable to open vault: " + err.Error())
 	}
+
+	return nil
 }
 
 func CreateBackup(ctx context.Context, c *ucli.Context) error {
+	if err := load(ctx); err != nil {
+		return err
+	}
+
 	args := c.Args()
 	renderer.BackupMessage()
 
@@ -53,10 +90,38 @@ func CreateBackup(ctx context.Context, c *ucli.Context) error {
 	return nil
 }
 
+func ListBackups(ctx context.Context, c *ucli.Context) error {
+	if err := load(ctx); err != nil {
+		return err
+	}
+
+	backups, err := passline.ListBackups()
+	if err != nil {
+		return err
+	}
+
+	if len(backups) == 0 {
+		renderer.NoBackupsMessage()
+		return nil
+	}
+
+	renderer.DisplayBackups(backups)
+	return nil
+}
+
 func AddItem(ctx context.Context, c *ucli.Context) error {
+	if err := load(ctx); err != nil {
+		return err
+	}
+
 	args := c.Args()
 	renderer.CreateMessage()
 
+	details, err := itemDetails(c)
+	if err != nil {
+		return err
+	}
+
 	// User input name
 	name, err := argOrInput(args, 0, "URL", "")
 	handle(err)
@@ -91,19 +156,31 @@ func AddItem(ctx context.Context, c *ucli.Context) error {
 		recoveryCodes = util.StringToArray(recoveryCodesString)
 	}
 
-	globalPassword := getPassword("Enter Global Password: ")
-	println()
+	globalPassword := getGlobalPassword(ctx)
 
-	credential, err := passline.AddItem(ctx, name, username, password, recoveryCodes, globalPassword)
-	if err != nil {
+	if c.Bool("refuse-breached") {
+		passline.SetRefuseBreached()
+	}
+
+	credential, err := passline.AddItem(ctx, name, username, password, recoveryCodes, details, globalPassword)
+	if refused, ok := err.(*core.BreachedError); ok {
+		renderer.BreachedPasswordRefused(refused.Count)
+		return nil
+	} else if err != nil {
 		return err
 	}
 
+	warnBreached(password, globalPassword)
+
 	renderer.DisplayCredential(credential)
 	return nil
 }
 
 func DeleteItem(ctx context.Context, c *ucli.Context) error {
+	if err := load(ctx); err != nil {
+		return err
+	}
+
 	// Get all Sites
 	names, err := passline.GetSiteNames(ctx)
 	if err != nil {
@@ -119,7 +196,7 @@ func DeleteItem(ctx context.Context, c *ucli.Context) error {
 	args := c.Args()
 	renderer.DeleteMessage()
 
-	item, err := selectItem(ctx, args, names)
+	item, err := selectItem(ctx, args)
 	if err != nil {
 		return err
 	}
@@ -139,6 +216,10 @@ func DeleteItem(ctx context.Context, c *ucli.Context) error {
 }
 
 func DisplayItem(ctx context.Context, c *ucli.Context) error {
+	if err := load(ctx); err != nil {
+		return err
+	}
+
 	// Get all Sites
 	names, err := passline.GetSiteNames(ctx)
 	if err != nil {
@@ -154,15 +235,14 @@ func DisplayItem(ctx context.Context, c *ucli.Context) error {
 	args := c.Args()
 	renderer.DisplayMessage()
 
-	item, err := selectItem(ctx, args, names)
+	item, err := selectItem(ctx, args)
 	handle(err)
 
 	credential, err := selectCredential(args, item)
 	handle(err)
 
 	// Get global password.
-	globalPassword := getPassword("Enter Global Password: ")
-	println()
+	globalPassword := getGlobalPassword(ctx)
 
 	// Check global password.
 	valid, err := passline.CheckPassword(ctx, globalPassword)
@@ -191,6 +271,10 @@ func DisplayItem(ctx context.Context, c *ucli.Context) error {
 }
 
 func EditItem(ctx context.Context, c *ucli.Context) error {
+	if err := load(ctx); err != nil {
+		return err
+	}
+
 	// Get all Sites
 	names, err := passline.GetSiteNames(ctx)
 	if err != nil {
@@ -206,7 +290,7 @@ func EditItem(ctx context.Context, c *ucli.Context) error {
 	args := c.Args()
 	renderer.DeleteMessage()
 
-	item, err := selectItem(ctx, args, names)
+	item, err := selectItem(ctx, args)
 	handle(err)
 
 	credential, err := selectCredential(args, item)
@@ -215,8 +299,7 @@ func EditItem(ctx context.Context, c *ucli.Context) error {
 	selectedUsername := credential.Username
 
 	// Get global password.
-	globalPassword := getPassword("Enter Global Password: ")
-	println()
+	globalPassword := getGlobalPassword(ctx)
 
 	// Check global password.
 	valid, err := passline.CheckPassword(ctx, globalPassword)
@@ -249,7 +332,32 @@ func EditItem(ctx context.Context, c *ucli.Context) error {
 		credential.RecoveryCodes = util.StringToArray(newRecoveryCodes)
 	}
 
-	err = passline.EditItem(ctx, item.Name, selectedUsername, credential, globalPassword)
+	// Get new urls, folder and tags of the item
+	urls, err := Input("Please enter urls []: (%s) ", storage.FormatURIs(item.URIs))
+	handle(err)
+
+	uris, err := parseURIs(util.StringToArray(urls))
+	handle(err)
+
+	folder, err := Input("Please enter a folder []: (%s) ", item.Folder)
+	handle(err)
+
+	tags, err := Input("Please enter tags []: (%s) ", util.ArrayToString(item.Tags))
+	handle(err)
+
+	// use one space to clear urls, folder or tags
+	details := core.Details{}
+	if urls != storage.FormatURIs(item.URIs) {
+		details.URIs = uris
+	}
+	if folder != item.Folder {
+		details.Folder = &folder
+	}
+	if tags != util.ArrayToString(item.Tags) {
+		details.Tags = util.StringToArray(tags)
+	}
+
+	err = passline.EditItem(ctx, item.Name, selectedUsername, credential, details, globalPassword)
 	handle(err)
 
 	renderer.SuccessfulChangedItem(item.Name, credential.Username)
@@ -258,9 +366,18 @@ func EditItem(ctx context.Context, c *ucli.Context) error {
 }
 
 func GenerateItem(ctx context.Context, c *ucli.Context) error {
+	if err := load(ctx); err != nil {
+		return err
+	}
+
 	args := c.Args()
 	renderer.GenerateMessage()
 
+	details, err := itemDetails(c)
+	if err != nil {
+		return err
+	}
+
 	// User input name
 	name, err := argOrInput(args, 0, "URL", "")
 	handle(err)
@@ -293,10 +410,9 @@ func GenerateItem(ctx context.Context, c *ucli.Context) error {
 		recoveryCodes = util.StringToArray(recoveryCodesString)
 	}
 
-	globalPassword := getPassword("Enter Global Password: ")
-	println()
+	globalPassword := getGlobalPassword(ctx)
 
-	credential, err := passline.GenerateItem(ctx, name, username, recoveryCodes, globalPassword)
+	credential, err := passline.GenerateItem(ctx, name, username, recoveryCodes, details, globalPassword)
 	handle(err)
 
 	err = clipboard.WriteAll(credential.Password)
@@ -310,6 +426,10 @@ func GenerateItem(ctx context.Context, c *ucli.Context) error {
 }
 
 func ListItems(ctx context.Context, c *ucli.Context) error {
+	if err := load(ctx); err != nil {
+		return err
+	}
+
 	args := c.Args()
 
 	if args.Len() >= 1 {
@@ -328,6 +448,13 @@ func ListItems(ctx context.Context, c *ucli.Context) error {
 
 		if len(items) == 0 {
 			renderer.NoItemsMessage()
+			return nil
+		}
+
+		items = filterItems(items, c.String("folder"), c.StringSlice("tag"))
+		if len(items) == 0 {
+			renderer.NoMatchingItemsMessage()
+			return nil
 		}
 		renderer.DisplayItems(items)
 	}
@@ -335,7 +462,263 @@ func ListItems(ctx context.Context, c *ucli.Context) error {
 	return nil
 }
 
+func ExportItems(ctx context.Context, c *ucli.Context) error {
+	if err := load(ctx); err != nil {
+		return err
+	}
+
+	args := c.Args()
+	renderer.ExportMessage()
+
+	format := c.String("format")
+	if !util.ArrayContains(exporter.Formats(), format) {
+		renderer.InvalidExportFormat(format, exporter.Formats())
+		return nil
+	}
+
+	names, err := passline.GetSiteNames(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Export a single item or a filtered subset
+	if item := c.String("item"); item != "" {
+		if !util.ArrayContains(names, item) {
+			renderer.InvalidName(item)
+			return nil
+		}
+		names = []string{item}
+	} else if filter := c.String("filter"); filter != "" {
+		names = util.FilterArray(names, filter)
+	}
+
+	if len(names) == 0 {
+		renderer.NoItemsMessage()
+		return nil
+	}
+
+	// User input path
+	path, err := argOrInput(args, 0, "Path", "")
+	if err != nil {
+		return err
+	}
+
+	renderer.ExportWarning(len(names))
+	confirmation, err := Input("Type \"export\" to continue []: ", "")
+	if err != nil {
+		return err
+	}
+
+	if confirmation != "export" {
+		renderer.ExportAborted()
+		return nil
+	}
+
+	// Get global password.
+	globalPassword := getGlobalPassword(ctx)
+
+	// Check global password.
+	valid, err := passline.CheckPassword(ctx, globalPassword)
+	if err != nil || !valid {
+		handle(err)
+	}
+
+	items, err := passline.ExportItems(ctx, names, globalPassword)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	err = exporter.Write(format, file, items)
+	if err != nil {
+		return err
+	}
+
+	renderer.SuccessfulExported(path, len(items))
+	return nil
+}
+
+func ImportItems(ctx context.Context, c *ucli.Context) error {
+	if err := load(ctx); err != nil {
+		return err
+	}
+
+	args := c.Args()
+	renderer.ImportMessage()
+
+	format := c.String("format")
+	if !util.ArrayContains(importer.Formats(), format) {
+		renderer.InvalidImportFormat(format, importer.Formats())
+		return nil
+	}
+
+	// User input path
+	path, err := argOrInput(args, 0, "Path", "")
+	if err != nil {
+		return err
+	}
+
+	items, err := importer.Parse(format, path)
+	if err != nil {
+		return err
+	}
+
+	if len(items) == 0 {
+		renderer.NoItemsMessage()
+		return nil
+	}
+
+	// Get global password.
+	globalPassword := getGlobalPassword(ctx)
+
+	// Check global password.
+	valid, err := passline.CheckPassword(ctx, globalPassword)
+	if err != nil || !valid {
+		handle(err)
+	}
+
+	imported, skipped, err := passline.ImportItems(ctx, items, globalPassword)
+	if err != nil {
+		return err
+	}
+
+	renderer.SuccessfulImported(imported, skipped)
+	return nil
+}
+
+func SearchItems(ctx context.Context, c *ucli.Context) error {
+	if err := load(ctx); err != nil {
+		return err
+	}
+
+	query, err := argOrInput(c.Args(), 0, "Query", "")
+	handle(err)
+
+	results, err := passline.Search(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	if len(results) == 0 {
+		renderer.NoMatchingItemsMessage()
+		return nil
+	}
+
+	for _, result := range results {
+		renderer.DisplaySearchResult(result.Item, result.Field, result.Value, result.Positions)
+	}
+
+	return nil
+}
+
+// FindURL lists the credentials of all items used for the url
+func FindURL(ctx context.Context, c *ucli.Context) error {
+	if err := load(ctx); err != nil {
+		return err
+	}
+
+	url, err := argOrInput(c.Args(), 0, "URL", "")
+	handle(err)
+
+	items, err := passline.FindURL(ctx, url)
+	if err != nil {
+		return err
+	}
+
+	if len(items) == 0 {
+		renderer.NoMatchingItemsMessage()
+		return nil
+	}
+
+	renderer.DisplayMatches(items)
+	return nil
+}
+
+// Audit reports weak, reused and old passwords
+func Audit(ctx context.Context, c *ucli.Context) error {
+	if err := load(ctx); err != nil {
+		return err
+	}
+
+	maxAge := c.Int("max-age")
+	if maxAge < 0 {
+		return errors.New("Max age must not be negative")
+	}
+
+	// Get global password.
+	globalPassword := getGlobalPassword(ctx)
+
+	// Check global password.
+	valid, err := passline.CheckPassword(ctx, globalPassword)
+	if err != nil || !valid {
+		handle(err)
+	}
+
+	report, err := passline.Audit(ctx, globalPassword, time.Duration(maxAge)*24*time.Hour)
+	if err != nil {
+		return err
+	}
+
+	if c.Bool("json") {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	for _, issue := range report.Issues {
+		renderer.AuditIssue(issue.Item, issue.Username, issue.Kind, issue.Detail)
+	}
+	renderer.AuditSummary(report.Score, report.Credentials, report.Counts, report.BreachChecked)
+	return nil
+}
+
+// TUI opens the full screen interface
+func TUI(ctx context.Context, c *ucli.Context) error {
+	if err := load(ctx); err != nil {
+		return err
+	}
+
+	return tui.Run(ctx, passline)
+}
+
+func Migrate(ctx context.Context, c *ucli.Context) error {
+	if err := load(ctx); err != nil {
+		return err
+	}
+
+	renderer.MigrateMessage()
+
+	to := c.String("to")
+	if !util.ArrayContains(storage.BackendNames(), to) {
+		renderer.InvalidStorage(to, storage.BackendNames())
+		return nil
+	}
+
+	// Get global password.
+	globalPassword := getGlobalPassword(ctx)
+
+	count, err := passline.Migrate(ctx, c.String("from"), to, globalPassword)
+	if err != nil {
+		return err
+	}
+
+	renderer.SuccessfulMigrated(count, to)
+	return nil
+}
+
 func RestoreBackup(ctx context.Context, c *ucli.Context) error {
+	if err := load(ctx); err != nil {
+		return err
+	}
+
 	args := c.Args()
 	renderer.RestoreMessage()
 
@@ -354,6 +737,50 @@ func RestoreBackup(ctx context.Context, c *ucli.Context) error {
 	return nil
 }
 
+func Sync(ctx context.Context, c *ucli.Context) error {
+	if err := load(ctx); err != nil {
+		return err
+	}
+
+	renderer.SyncMessage()
+
+	err := passline.Sync(ctx)
+	if err != nil {
+		return err
+	}
+
+	if c.Bool("keep-local") || c.Bool("keep-remote") {
+		err = passline.ResolveConflicts(ctx, c.Bool("keep-local"))
+		if err != nil {
+			return err
+		}
+	}
+
+	conflicts := passline.Conflicts()
+	if len(conflicts) > 0 {
+		renderer.DisplayConflicts(conflicts)
+		return nil
+	}
+
+	renderer.SuccessfulSynced()
+	return nil
+}
+
+func ListStorages(ctx context.Context, c *ucli.Context) error {
+	cfg, err := config.Get()
+	if err != nil {
+		// Show the storages of the top level config instead
+		renderer.VaultError(err)
+		cfg, err = config.Read()
+		if err != nil {
+			return err
+		}
+	}
+
+	renderer.DisplayBackends(storage.Backends(), cfg.Storage)
+	return nil
+}
+
 func getAdvancedParamters(ctx context.Context) error {
 	length, err := Input("Please enter the length of the password []: (%s)", "20")
 	if err != nil {
@@ -386,24 +813,36 @@ func argOrInput(args ucli.Args, index int, message string, defaultValue string)
 }
 
 func argOrSelect(args ucli.Args, index int, message string, items []string) (string, error) {
+	return argOrSelectWith(args, index, message, items, SelectOptions{})
+}
+
+// argOrSelectWith is argOrSelect with options for the interactive selection
+func argOrSelectWith(args ucli.Args, index int, message string, items []string, options SelectOptions) (string, error) {
 	input := ""
 	if args.Len()-1 >= index {
 		input = args.Get(index)
 
 		// if input is no item name use as filter
 		if !util.ArrayContains(items, input) {
-			items = util.FilterArray(items, input)
-			if len(items) == 0 {
+			matches := util.FuzzyFilter(items, input)
+			if len(matches) == 0 {
 				fmt.Printf("No items with filter: %v found\n", input)
 				return "", errors.New("No items found")
 			}
+
+			if len(matches) == 1 {
+				fmt.Printf("Selected %s: %s\n", message, items[matches[0]])
+				return items[matches[0]], nil
+			}
+
+			options.Query = input
 			input = ""
 		}
 	}
 	if input == "" {
 		if len(items) > 1 {
 			message := fmt.Sprintf("Please select a %s: ", message)
-			selection, err := Select(message, items)
+			selection, err := SelectWith(message, items, options)
 			if err != nil {
 				return "", err
 			}
@@ -419,14 +858,120 @@ func argOrSelect(args ucli.Args, index int, message string, items []string) (str
 	return input, nil
 }
 
+// getGlobalPassword asks for the global password and returns the key the
+// items of the vault are encrypted with
+func getGlobalPassword(ctx context.Context) []byte {
+	var password []byte
+	if passline.PasswordRequired() {
+		password = storage.PasswordPrompt()
+	}
+
+	key, err := passline.UnlockKey(ctx, password)
+	if err != nil {
+		renderer.UnlockError(err)
+		os.Exit(1)
+	}
+
+	return key
+}
+
+// warnBreached shows if a password that was not refused is breached
+func warnBreached(password string, globalPassword []byte) {
+	if !passline.BreachCheck() || passline.RefuseBreached() {
+		return
+	}
+
+	count, err := passline.Breached(password, globalPassword)
+	if err != nil {
+		renderer.BreachError(err)
+	} else if count > 0 {
+		renderer.BreachedPassword(count)
+	}
+}
+
+// itemDetails reads the url, folder and tag flags, unset flags keep the
+// values of an existing item
+func itemDetails(c *ucli.Context) (core.Details, error) {
+	details := core.Details{}
+
+	if c.IsSet("url") {
+		uris, err := parseURIs(c.StringSlice("url"))
+		if err != nil {
+			return details, err
+		}
+		details.URIs = uris
+	}
+
+	if c.IsSet("folder") {
+		folder := c.String("folder")
+		details.Folder = &folder
+	}
+
+	if c.IsSet("tag") {
+		details.Tags = c.StringSlice("tag")
+	}
+
+	return details, nil
+}
+
+// parseURIs reads urls written as [rule:]url
+func parseURIs(values []string) ([]storage.URI, error) {
+	uris, err := storage.ParseURIs(values)
+	if err != nil {
+		renderer.InvalidURL(strings.Join(values, ","), err)
+		return nil, err
+	}
+
+	return uris, nil
+}
+
+// filterItems returns the items in the folder that carry all tags
+func filterItems(items []storage.Item, folder string, tags []string) []storage.Item {
+	filtered := []storage.Item{}
+	for _, item := range items {
+		if !item.InFolder(folder) {
+			continue
+		}
+
+		matches := true
+		for _, tag := range tags {
+			if !item.HasTag(tag) {
+				matches = false
+				break
+			}
+		}
+
+		if matches {
+			filtered = append(filtered, item)
+		}
+	}
+
+	return filtered
+}
+
 func handle(err error) {
 	if err != nil {
 		os.Exit(1)
 	}
 }
 
-func selectItem(ctx context.Context, args ucli.Args, names []string) (storage.Item, error) {
-	name, err := argOrSelect(args, 0, "URL", names)
+// selectItem returns the item named by the first argument or lets the user
+// select one, grouped by folder
+func selectItem(ctx context.Context, args ucli.Args) (storage.Item, error) {
+	items, err := passline.GetSites(ctx)
+	handle(err)
+
+	sort.Sort(storage.ByFolder(items))
+
+	names, folders := []string{}, []string{}
+	usernames := map[string][]string{}
+	for _, item := range items {
+		names = append(names, item.Name)
+		folders = append(folders, item.Folder)
+		usernames[item.Name] = item.GetUsernameArray()
+	}
+
+	name, err := argOrSelectWith(args, 0, "URL", names, SelectOptions{Groups: folders, Preview: previewUsernames(usernames)})
 	handle(err)
 
 	// Get item
@@ -439,6 +984,13 @@ func selectItem(ctx context.Context, args ucli.Args, names []string) (storage.It
 	return item, nil
 }
 
+// previewUsernames returns a preview listing the usernames of the item
+func previewUsernames(usernames map[string][]string) func(string) string {
+	return func(item string) string {
+		return "Usernames: " + strings.Join(usernames[item], ", ")
+	}
+}
+
 func selectCredential(args ucli.Args, item storage.Item) (storage.Credential, error) {
 	username, err := argOrSelect(args, 1, "Username/Login", item.GetUsernameArray())
 	handle(err)
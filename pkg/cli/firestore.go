@@ -0,0 +1,73 @@
+package cli
+
+import (
+	"context"
+	"errors"
+
+	ucli "github.com/urfave/cli/v2"
+
+	"github.com/perryrh0dan/passline/pkg/config"
+	"github.com/perryrh0dan/passline/pkg/renderer"
+	"github.com/perryrh0dan/passline/pkg/storage"
+)
+
+func ListFirestoreVaults(ctx context.Context, c *ucli.Context) error {
+	cfg, err := config.Get()
+	if err != nil {
+		return err
+	}
+
+	fs, err := storage.NewFirestore(ctx)
+	if err != nil {
+		return err
+	}
+
+	vaults, err := fs.Vaults(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(vaults) == 0 {
+		renderer.NoFirestoreVaultsMessage()
+		return nil
+	}
+
+	renderer.DisplayFirestoreVaults(vaults, cfg.Firestore.Vault)
+	return nil
+}
+
+func UseFirestoreVault(ctx context.Context, c *ucli.Context) error {
+	args := c.Args()
+
+	// An empty name switches back to the top level collection
+	name, err := argOrInput(args, 0, "Vault", "")
+	if err != nil {
+		return err
+	}
+
+	err = config.Update(func(cfg *config.Config) error {
+		if cfg.Vault == config.MainVault {
+			cfg.Firestore.Vault = name
+			return nil
+		}
+
+		// The firestore section of a vault replaces the top level one
+		vault, ok := cfg.Vaults[cfg.Vault]
+		if !ok {
+			return errors.New("Unknown vault " + cfg.Vault)
+		}
+		if vault.Firestore == nil {
+			firestore := cfg.Firestore
+			vault.Firestore = &firestore
+		}
+		vault.Firestore.Vault = name
+		cfg.Vaults[cfg.Vault] = vault
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	renderer.SuccessfulChangedFirestoreVault(name)
+	return nil
+}
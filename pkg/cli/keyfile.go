@@ -0,0 +1,50 @@
+package cli
+
+import (
+	"context"
+
+	ucli "github.com/urfave/cli/v2"
+
+	"github.com/perryrh0dan/passline/pkg/renderer"
+	"github.com/perryrh0dan/passline/pkg/storage"
+)
+
+func GenerateKeyFile(ctx context.Context, c *ucli.Context) error {
+	if err := load(ctx); err != nil {
+		return err
+	}
+
+	args := c.Args()
+
+	path, err := argOrInput(args, 0, "Path", "")
+	if err != nil {
+		return err
+	}
+
+	err = passline.GenerateKeyFile(path)
+	if err != nil {
+		return err
+	}
+
+	renderer.SuccessfulGeneratedKeyFile(path)
+	return nil
+}
+
+func ChangeKeyFile(ctx context.Context, c *ucli.Context) error {
+	if err := load(ctx); err != nil {
+		return err
+	}
+
+	// Without a path the key file is removed
+	path := c.Args().First()
+
+	password := storage.PasswordPrompt()
+
+	err := passline.ChangeKeyFile(ctx, path, password)
+	if err != nil {
+		return err
+	}
+
+	renderer.SuccessfulChangedKeyFile(path)
+	return nil
+}
@@ -3,19 +3,68 @@ package cli
 import (
 	"errors"
 	"fmt"
+	"os"
 
 	"github.com/eiannone/keyboard"
 	"github.com/fatih/color"
+	"github.com/perryrh0dan/passline/pkg/util"
+	"golang.org/x/crypto/ssh/terminal"
 )
 
+// SelectOptions change how Select shows the items
+type SelectOptions struct {
+	// Groups holds the header of every item at the same index, items of a
+	// group have to be next to each other. Groups are shown while the filter
+	// is empty.
+	Groups []string
+	// Query is the initial filter
+	Query string
+	// Preview returns a line shown below the highlighted item
+	Preview func(item string) string
+}
+
+// picker is the state of an interactive selection
+type picker struct {
+	items   []string
+	options SelectOptions
+	query   []rune
+	matches []int
+	cursor  int
+	offset  int
+	height  int
+	width   int
+	lines   int
+}
+
+// row is a line of the picker, either an item or a group header
+type row struct {
+	item   int
+	header string
+}
+
 func Select(message string, items []string) (int, error) {
-	selected := 0
+	return SelectWith(message, items, SelectOptions{})
+}
+
+// SelectWith lets the user pick one of the items and returns its index.
+// Typing filters the items, the list scrolls within the terminal height.
+func SelectWith(message string, items []string, options SelectOptions) (int, error) {
+	p := &picker{items: items, options: options, query: []rune(options.Query), height: 10, width: 80}
+	if width, height, err := terminal.GetSize(int(os.Stdout.Fd())); err == nil {
+		// Message, filter and preview line are shown besides the items
+		p.height = height - 4
+		p.width = width
+	}
+	if p.height < 3 {
+		p.height = 3
+	}
+	p.filter()
 
 	// Print Message
 	fmt.Println(message)
 
 	// Print Initial Selection
-	printSelect(items, selected)
+	p.render()
 
 	// Open keyboard
 	err := keyboard.Open()
@@ -29,42 +78,171 @@ func Select(message string, items []string) (int, error) {
 	defer showCursor()
 
 	for {
-		_, key, _ := keyboard.GetKey()
-		update := false
+		char, key, err := keyboard.GetKey()
+		if err != nil {
+			return -1, err
+		}
+
 		switch key {
-		case keyboard.KeyEsc:
-			return -1, errors.New("Canceled")
-		case keyboard.KeyCtrlC:
+		case keyboard.KeyEsc, keyboard.KeyCtrlC:
+			clearLines(p.lines + 1)
 			return -1, errors.New("Canceled")
 		case keyboard.KeyEnter:
-			clearLines(len(items) + 1)
-			return selected, nil
-		case keyboard.KeyArrowUp:
-			if selected > 0 {
-				selected--
-				update = true
+			if len(p.matches) == 0 {
+				continue
 			}
-		case keyboard.KeyArrowDown:
-			if selected < len(items)-1 {
-				selected++
-				update = true
+			clearLines(p.lines + 1)
+			return p.matches[p.cursor], nil
+		case keyboard.KeyArrowUp, keyboard.KeyCtrlK, keyboard.KeyCtrlP:
+			p.move(-1)
+		case keyboard.KeyArrowDown, keyboard.KeyCtrlJ, keyboard.KeyCtrlN:
+			p.move(1)
+		case keyboard.KeyPgup, keyboard.KeyCtrlB:
+			p.move(-p.height)
+		case keyboard.KeyPgdn, keyboard.KeyCtrlF:
+			p.move(p.height)
+		case keyboard.KeyCtrlU:
+			p.move(-p.height / 2)
+		case keyboard.KeyCtrlD:
+			p.move(p.height / 2)
+		case keyboard.KeyHome:
+			p.move(-len(p.matches))
+		case keyboard.KeyEnd:
+			p.move(len(p.matches))
+		case keyboard.KeyBackspace, keyboard.KeyBackspace2:
+			if len(p.query) == 0 {
+				continue
 			}
+			p.query = p.query[:len(p.query)-1]
+			p.filter()
+		case keyboard.KeySpace:
+			p.query = append(p.query, ' ')
+			p.filter()
+		default:
+			if char == 0 {
+				continue
+			}
+			p.query = append(p.query, char)
+			p.filter()
+		}
+
+		p.render()
+	}
+}
+
+// filter updates the matches after the query changed, the best match is
+// highlighted
+func (p *picker) filter() {
+	p.cursor = 0
+	p.offset = 0
+
+	if len(p.query) == 0 {
+		p.matches = make([]int, len(p.items))
+		for i := range p.items {
+			p.matches[i] = i
 		}
+		return
+	}
+
+	p.matches = util.FuzzyFilter(p.items, string(p.query))
+}
+
+func (p *picker) move(n int) {
+	p.cursor += n
+	if p.cursor >= len(p.matches) {
+		p.cursor = len(p.matches) - 1
+	}
+	if p.cursor < 0 {
+		p.cursor = 0
+	}
+}
+
+// rows returns the lines of all matches with the group headers
+func (p *picker) rows() []row {
+	grouped := p.options.Groups != nil && len(p.query) == 0
 
-		if update {
-			moveCursorUp(len(items))
-			printSelect(items, selected)
+	rows := []row{}
+	for i, item := range p.matches {
+		if grouped && p.options.Groups[item] != "" && (i == 0 || p.options.Groups[p.matches[i-1]] != p.options.Groups[item]) {
+			rows = append(rows, row{item: -1, header: p.options.Groups[item]})
 		}
+		rows = append(rows, row{item: i})
 	}
+
+	return rows
 }
 
-func printSelect(items []string, selected int) {
-	for index, item := range items {
-		if index != selected {
-			fmt.Printf("[ ] %s\n", item)
+func (p *picker) render() {
+	if p.lines > 0 {
+		clearLines(p.lines)
+	}
+
+	rows := p.rows()
+
+	// Scroll so the highlighted item and its group header are visible
+	selected := 0
+	for i, r := range rows {
+		if r.item == p.cursor {
+			selected = i
+			break
+		}
+	}
+	if selected > 0 && rows[selected-1].item == -1 {
+		selected--
+	}
+	if selected < p.offset {
+		p.offset = selected
+	}
+	for i, r := range rows {
+		if r.item == p.cursor && i >= p.offset+p.height {
+			p.offset = i - p.height + 1
+		}
+	}
+
+	end := p.offset + p.height
+	if end > len(rows) {
+		end = len(rows)
+	}
+
+	fmt.Printf("> %s %s\n", string(p.query), color.HiBlackString("(%d/%d)", len(p.matches), len(p.items)))
+	p.lines = 1
+
+	for _, r := range rows[p.offset:end] {
+		p.lines++
+		if r.item == -1 {
+			color.New(color.FgBlue).Printf("%s\n", p.truncate(r.header+"/", 0))
+			continue
+		}
+
+		indent := ""
+		item := p.matches[r.item]
+		if p.options.Groups != nil && len(p.query) == 0 && p.options.Groups[item] != "" {
+			indent = "  "
+		}
+
+		if r.item != p.cursor {
+			fmt.Printf("%s[ ] %s\n", indent, p.truncate(p.items[item], len(indent)+4))
 		} else {
 			d := color.New(color.FgGreen)
-			d.Printf("[x] %s\n", item)
+			d.Printf("%s[x] %s\n", indent, p.truncate(p.items[item], len(indent)+4))
 		}
 	}
+
+	if p.options.Preview != nil && len(p.matches) > 0 {
+		p.lines++
+		preview := p.options.Preview(p.items[p.matches[p.cursor]])
+		fmt.Printf("%s\n", color.HiBlackString(p.truncate(preview, 0)))
+	}
+}
+
+// truncate shortens text so a line with prefix characters before it fits
+// the terminal, lines must not wrap or clearing them fails
+func (p *picker) truncate(text string, prefix int) string {
+	max := p.width - prefix - 1
+	runes := []rune(text)
+	if max < 1 || len(runes) <= max {
+		return text
+	}
+
+	return string(runes[:max-1]) + "…"
 }
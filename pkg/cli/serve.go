@@ -0,0 +1,143 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+
+	ucli "github.com/urfave/cli/v2"
+
+	"github.com/perryrh0dan/passline/pkg/config"
+	"github.com/perryrh0dan/passline/pkg/renderer"
+	"github.com/perryrh0dan/passline/pkg/server"
+)
+
+const defaultServerAddress = "127.0.0.1:7744"
+
+func Serve(ctx context.Context, c *ucli.Context) error {
+	cfg, err := config.Get()
+	if err != nil {
+		return err
+	}
+
+	address := cfg.Server.Address
+	if c.String("address") != "" {
+		address = c.String("address")
+	}
+	if address == "" {
+		address = defaultServerAddress
+	}
+
+	handler, err := server.New(cfg.Server.Directory)
+	if err != nil {
+		return err
+	}
+
+	devices, err := server.ListDevices(cfg.Server.Directory)
+	if err != nil {
+		return err
+	}
+
+	if len(devices) == 0 {
+		renderer.NoDevicesMessage()
+	}
+
+	// Other hosts are only served with https or device tokens
+	if !isLoopback(address) && cfg.Server.CertFile == "" && len(devices) == 0 {
+		return errors.New("Refusing to serve " + address + " without https or devices, use a loopback address")
+	}
+
+	srv := &http.Server{Addr: address, Handler: handler}
+	go func() {
+		<-ctx.Done()
+		srv.Shutdown(context.Background())
+	}()
+
+	renderer.ServeMessage(address)
+	if cfg.Server.CertFile != "" {
+		err = srv.ListenAndServeTLS(cfg.Server.CertFile, cfg.Server.KeyFile)
+	} else {
+		err = srv.ListenAndServe()
+	}
+
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
+
+// isLoopback reports if address only listens on the local host
+func isLoopback(address string) bool {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return false
+	}
+
+	if host == "localhost" {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func AddDevice(ctx context.Context, c *ucli.Context) error {
+	cfg, err := config.Get()
+	if err != nil {
+		return err
+	}
+
+	name, err := argOrInput(c.Args(), 0, "Device name", "")
+	if err != nil {
+		return err
+	}
+
+	token, err := server.AddDevice(cfg.Server.Directory, name)
+	if err != nil {
+		return err
+	}
+
+	renderer.SuccessfulAddedDevice(name, token)
+	return nil
+}
+
+func ListDevices(ctx context.Context, c *ucli.Context) error {
+	cfg, err := config.Get()
+	if err != nil {
+		return err
+	}
+
+	devices, err := server.ListDevices(cfg.Server.Directory)
+	if err != nil {
+		return err
+	}
+
+	if len(devices) == 0 {
+		renderer.NoDevicesMessage()
+		return nil
+	}
+
+	renderer.DisplayDevices(devices)
+	return nil
+}
+
+func RemoveDevice(ctx context.Context, c *ucli.Context) error {
+	cfg, err := config.Get()
+	if err != nil {
+		return err
+	}
+
+	name, err := argOrInput(c.Args(), 0, "Device name", "")
+	if err != nil {
+		return err
+	}
+
+	err = server.RemoveDevice(cfg.Server.Directory, name)
+	if err != nil {
+		return err
+	}
+
+	renderer.SuccessfulRemovedDevice(name)
+	return nil
+}
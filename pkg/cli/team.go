@@ -0,0 +1,120 @@
+package cli
+
+import (
+	"context"
+
+	ucli "github.com/urfave/cli/v2"
+
+	"github.com/perryrh0dan/passline/pkg/renderer"
+	"github.com/perryrh0dan/passline/pkg/storage"
+)
+
+func CreateIdentity(ctx context.Context, c *ucli.Context) error {
+	if err := load(ctx); err != nil {
+		return err
+	}
+
+	publicKey, err := passline.PublicKey()
+	if err == nil {
+		renderer.DisplayPublicKey(publicKey)
+		return nil
+	}
+
+	password := storage.PasswordPrompt()
+
+	publicKey, err = passline.CreateIdentity(password)
+	if err != nil {
+		return err
+	}
+
+	renderer.DisplayPublicKey(publicKey)
+	return nil
+}
+
+func InitTeam(ctx context.Context, c *ucli.Context) error {
+	if err := load(ctx); err != nil {
+		return err
+	}
+
+	args := c.Args()
+
+	name, err := argOrInput(args, 0, "Name", "")
+	if err != nil {
+		return err
+	}
+
+	password := storage.PasswordPrompt()
+
+	err = passline.InitTeam(ctx, name, password)
+	if err != nil {
+		return err
+	}
+
+	renderer.SuccessfulInitializedTeam()
+	return nil
+}
+
+func ListMembers(ctx context.Context, c *ucli.Context) error {
+	if err := load(ctx); err != nil {
+		return err
+	}
+
+	members, err := passline.Members(ctx)
+	if err != nil {
+		return err
+	}
+
+	renderer.DisplayMembers(members)
+	return nil
+}
+
+func AddMember(ctx context.Context, c *ucli.Context) error {
+	if err := load(ctx); err != nil {
+		return err
+	}
+
+	args := c.Args()
+
+	name, err := argOrInput(args, 0, "Name", "")
+	if err != nil {
+		return err
+	}
+
+	publicKey, err := argOrInput(args, 1, "Public key", "")
+	if err != nil {
+		return err
+	}
+
+	vaultKey := getGlobalPassword(ctx)
+
+	err = passline.AddMember(ctx, name, publicKey, vaultKey)
+	if err != nil {
+		return err
+	}
+
+	renderer.SuccessfulAddedMember(name)
+	return nil
+}
+
+func RemoveMember(ctx context.Context, c *ucli.Context) error {
+	if err := load(ctx); err != nil {
+		return err
+	}
+
+	args := c.Args()
+
+	name, err := argOrInput(args, 0, "Name", "")
+	if err != nil {
+		return err
+	}
+
+	vaultKey := getGlobalPassword(ctx)
+
+	err = passline.RemoveMember(ctx, name, vaultKey)
+	if err != nil {
+		return err
+	}
+
+	renderer.SuccessfulRemovedMember(name)
+	return nil
+}
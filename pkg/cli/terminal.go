@@ -19,7 +19,7 @@ func getPassword(prompt string) []byte {
 	}
 
 	// Restore it in the event of an interrupt.
-	c := make(chan os.Signal)
+	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt, os.Kill)
 	go func() {
 		<-c
@@ -0,0 +1,117 @@
+package cli
+
+import (
+	"context"
+	"errors"
+
+	ucli "github.com/urfave/cli/v2"
+
+	"github.com/perryrh0dan/passline/pkg/config"
+	"github.com/perryrh0dan/passline/pkg/renderer"
+	"github.com/perryrh0dan/passline/pkg/storage"
+	"github.com/perryrh0dan/passline/pkg/util"
+)
+
+func ListVaults(ctx context.Context, c *ucli.Context) error {
+	// The vaults are listed from the file, so a broken one can be fixed
+	cfg, err := config.Read()
+	if err != nil {
+		return err
+	}
+
+	renderer.DisplayVaults(cfg)
+	return nil
+}
+
+func AddVault(ctx context.Context, c *ucli.Context) error {
+	args := c.Args()
+
+	name, err := argOrInput(args, 0, "Name", "")
+	if err != nil {
+		return err
+	}
+
+	if name == config.MainVault {
+		return errors.New("Vault " + name + " is reserved for the top level config")
+	}
+
+	if !util.ArrayContains(storage.BackendNames(), c.String("storage")) {
+		renderer.InvalidStorage(c.String("storage"), storage.BackendNames())
+		return nil
+	}
+
+	err = config.Update(func(cfg *config.Config) error {
+		if _, ok := cfg.Vaults[name]; ok {
+			return errors.New("Vault " + name + " already exists")
+		}
+
+		if cfg.Vaults == nil {
+			cfg.Vaults = map[string]config.Vault{}
+		}
+
+		cfg.Vaults[name] = config.Vault{Storage: c.String("storage"), Encryption: c.String("encryption"), Directory: c.String("directory")}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	renderer.SuccessfulAddedVault(name)
+	return nil
+}
+
+func RemoveVault(ctx context.Context, c *ucli.Context) error {
+	args := c.Args()
+
+	name, err := argOrInput(args, 0, "Name", "")
+	if err != nil {
+		return err
+	}
+
+	err = config.Update(func(cfg *config.Config) error {
+		if _, ok := cfg.Vaults[name]; !ok {
+			return errors.New("Unknown vault " + name)
+		}
+
+		delete(cfg.Vaults, name)
+		if cfg.DefaultVault == name {
+			cfg.DefaultVault = ""
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	renderer.SuccessfulRemovedVault(name)
+	return nil
+}
+
+func DefaultVault(ctx context.Context, c *ucli.Context) error {
+	args := c.Args()
+
+	name, err := argOrInput(args, 0, "Name", config.MainVault)
+	if err != nil {
+		return err
+	}
+
+	err = config.Update(func(cfg *config.Config) error {
+		if name == config.MainVault {
+			cfg.DefaultVault = ""
+			return nil
+		}
+
+		if _, ok := cfg.Vaults[name]; !ok {
+			return errors.New("Unknown vault " + name)
+		}
+
+		cfg.DefaultVault = name
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	renderer.SuccessfulChangedDefaultVault(name)
+	return nil
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
 	"io/ioutil"
 	"os"
 	"path"
@@ -14,10 +15,174 @@ type Config struct {
 	AutoClip  bool
 	NoColor   bool
 	NoSymbols bool
+	// Keep a local replica of remote storages to work offline
+	OfflineCache bool
+	// How the items are encrypted, password, team or age
+	Encryption string
+	// Key file that is required in addition to the global password
+	KeyFile string
+	// Key pair of the user for team vaults, defaults to <Directory>/identity.json
+	Identity string
+	// Named profiles with their own storage and master password
+	Vaults map[string]Vault
+	// Vault used without --vault, empty for the main vault
+	DefaultVault string
+	Backup       Backup
+	Breach       Breach
+	Age          Age
+	Kdbx         Kdbx
+	Firestore    Firestore
+	Git          Git
+	Sqlite       Sqlite
+	S3           S3
+	WebDAV       WebDAV
+	Remote       Remote
+	Server       Server
+
+	// Name of the selected vault, set by Get
+	Vault string `json:"-"`
+}
+
+// MainVault is the name of the vault configured at the top level
+const MainVault = "main"
+
+// Vault is a named profile with its own storage and master password. Unset
+// storage settings are taken from the top level config, the encryption and
+// key file belong to the vault only.
+type Vault struct {
+	// Directory of the vault, defaults to <Directory>/vaults/<name>
+	Directory string
+	Storage   string
+	// How the items are encrypted, defaults to password
+	Encryption string
+	// Key file of the vault, empty for none
+	KeyFile   string
+	Age       *Age
+	Kdbx      *Kdbx
+	Firestore *Firestore
+	Git       *Git
+	Sqlite    *Sqlite
+	S3        *S3
+	WebDAV    *WebDAV
+	Remote    *Remote
+}
+
+// Age configures vaults with age encryption
+type Age struct {
+	// Public keys (age1...) the vault key is encrypted to, a passphrase is used if empty
+	Recipients []string
+	// Identity file with the secret key, defaults to <Directory>/age.txt
+	Identity string
+}
+
+// Firestore configures the firestore storage
+type Firestore struct {
+	// Google Cloud project, detected from the credentials file if empty
+	Project string
+	// Collection of the items if no vault is set
+	Collection string
+	// Keep the items in vaults/<Vault>/items, so a project can be shared
+	Vault string
+	// Service account key, defaults to <Directory>/firestore.json
+	CredentialsFile string
+}
+
+// Kdbx configures the KeePass storage
+type Kdbx struct {
+	// Path of the database, defaults to <Directory>/storage/passline.kdbx
+	File string
+}
+
+// Backup configures automatic backups
+type Backup struct {
+	// Directory for automatic backups, defaults to <Directory>/backups.
+	// Named vaults keep their backups in a subdirectory of their name.
+	Directory string
+	// Create a backup after every n mutations, 0 disables the trigger
+	Mutations int
+	// Create a backup when the last one is older than n days, 0 disables the trigger
+	MaxAge int
+	// Number of most recent backups to keep, 0 keeps all
+	KeepLast int
+	// Number of months for which the newest backup is kept
+	KeepMonthly int
+}
+
+// Breach configures the offline check of passwords against the Have I Been
+// Pwned password dataset
+type Breach struct {
+	// Directory with the downloaded range files, named by hash prefix like 5BAA6.txt
+	Directory string
+	// Url of a local server with the range api, used if no directory is set
+	URL string
+	// Refuse to add passwords that are known to be breached
+	Refuse bool
+}
+
+// Sqlite configures the sqlite storage
+type Sqlite struct {
+	// Path of the database, defaults to <Directory>/storage/storage.db
+	File string
+}
+
+// S3 configures the S3 compatible storage
+type S3 struct {
+	// Url of the service, e.g. https://s3.amazonaws.com or http://localhost:9000
+	Endpoint string
+	Region   string
+	Bucket   string
+	// Object key of the vault, defaults to passline/storage.json
+	Key       string
+	AccessKey string
+	SecretKey string
+	// Address the bucket in the path instead of the host name, used by MinIO
+	PathStyle bool
+}
+
+// WebDAV configures the WebDAV storage
+type WebDAV struct {
+	// Url of the vault file, e.g. https://cloud.example.com/remote.php/dav/files/user/passline.json
+	URL      string
+	Username string
+	Password string
+}
+
+// Remote configures the storage on a passline sync server
+type Remote struct {
+	// Url of the server, e.g. https://passline.example.com
+	URL string
+	// Token of this device, created with passline serve device add
+	Token string
+}
+
+// Server configures passline serve
+type Server struct {
+	// Address to listen on
+	Address string
+	// Directory for items and tokens, defaults to <Directory>/server
+	Directory string
+	// Certificate and key to serve https
+	CertFile string
+	KeyFile  string
+}
+
+// Git configures the git storage
+type Git struct {
+	// Path of the repository, defaults to <Directory>/git
+	Directory string
+	// Url of the remote used by sync
+	Remote string
+	// Branch to sync with, defaults to master
+	Branch string
+	// How to integrate remote changes, rebase or merge
+	Strategy string
 }
 
 var configFile string
 
+// selectedVault overrides the default vault
+var selectedVault string
+
 func init() {
 	homeDir, err := os.UserHomeDir()
 	if err == nil {
@@ -27,6 +192,13 @@ func init() {
 	ensureConfigFile()
 }
 
+// UseFile reads and writes the config at file instead of ~/.passline.json,
+// it is created if it does not exist
+func UseFile(file string) {
+	configFile = file
+	ensureConfigFile()
+}
+
 func ensureConfigFile() {
 	_, err := os.Stat(configFile)
 	if err == nil {
@@ -35,7 +207,7 @@ func ensureConfigFile() {
 
 	config := new()
 	file, _ := json.MarshalIndent(config, "", " ")
-	_ = ioutil.WriteFile(configFile, file, 0644)
+	_ = ioutil.WriteFile(configFile, file, 0600)
 }
 
 func formatPasslineDir(dirPath string) (string, error) {
@@ -46,13 +218,84 @@ func formatPasslineDir(dirPath string) (string, error) {
 	return path.Join(homeDir, strings.Replace(dirPath, "~", "", 1), ".passline"), nil
 }
 
+func formatHomeDir(dirPath string) (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return path.Join(homeDir, strings.Replace(dirPath, "~", "", 1)), nil
+}
+
 func new() Config {
 	return Config{
-		Directory: "~",
-		Storage:   "local",
-		AutoClip:  true,
-		NoColor:   false,
-		NoSymbols: false,
+		Directory:    "~",
+		Storage:      "local",
+		AutoClip:     true,
+		NoColor:      false,
+		NoSymbols:    false,
+		Encryption:   "password",
+		KeyFile:      "",
+		Identity:     "",
+		Vaults:       map[string]Vault{},
+		DefaultVault: "",
+		Backup: Backup{
+			Directory:   "",
+			Mutations:   0,
+			MaxAge:      0,
+			KeepLast:    10,
+			KeepMonthly: 12,
+		},
+		Breach: Breach{
+			Directory: "",
+			URL:       "",
+			Refuse:    false,
+		},
+		Age: Age{
+			Recipients: []string{},
+			Identity:   "",
+		},
+		Kdbx: Kdbx{
+			File: "",
+		},
+		Firestore: Firestore{
+			Project:         "",
+			Collection:      "passline",
+			Vault:           "",
+			CredentialsFile: "",
+		},
+		Git: Git{
+			Directory: "",
+			Remote:    "",
+			Branch:    "master",
+			Strategy:  "rebase",
+		},
+		Sqlite: Sqlite{
+			File: "",
+		},
+		S3: S3{
+			Endpoint:  "",
+			Region:    "us-east-1",
+			Bucket:    "",
+			Key:       "passline/storage.json",
+			AccessKey: "",
+			SecretKey: "",
+			PathStyle: true,
+		},
+		WebDAV: WebDAV{
+			URL:      "",
+			Username: "",
+			Password: "",
+		},
+		Remote: Remote{
+			URL:   "",
+			Token: "",
+		},
+		Server: Server{
+			Address:   "127.0.0.1:7744",
+			Directory: "",
+			CertFile:  "",
+			KeyFile:   "",
+		},
 	}
 }
 
@@ -70,5 +313,268 @@ func Get() (*Config, error) {
 		}
 	}
 
+	if config.Identity == "" {
+		config.Identity = path.Join(config.Directory, "identity.json")
+	} else if strings.HasPrefix(config.Identity, "~") {
+		var err error
+		config.Identity, err = formatHomeDir(config.Identity)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Identities of the user are kept in the main directory
+	mainDirectory := config.Directory
+
+	err := config.selectVault()
+	if err != nil {
+		return nil, err
+	}
+
+	if config.Age.Identity == "" {
+		config.Age.Identity = path.Join(mainDirectory, "age.txt")
+	}
+
+	if config.Backup.Directory == "" {
+		config.Backup.Directory = path.Join(config.Directory, "backups")
+	} else {
+		if strings.HasPrefix(config.Backup.Directory, "~") {
+			var err error
+			config.Backup.Directory, err = formatHomeDir(config.Backup.Directory)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		// The directory is shared by all vaults, named ones use a subdirectory
+		if config.Vault != MainVault {
+			config.Backup.Directory = path.Join(config.Backup.Directory, config.Vault)
+		}
+	}
+
+	if config.Firestore.CredentialsFile == "" {
+		config.Firestore.CredentialsFile = path.Join(config.Directory, "firestore.json")
+	} else if strings.HasPrefix(config.Firestore.CredentialsFile, "~") {
+		var err error
+		config.Firestore.CredentialsFile, err = formatHomeDir(config.Firestore.CredentialsFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if config.Firestore.Collection == "" {
+		config.Firestore.Collection = "passline"
+	}
+
+	if strings.HasPrefix(config.KeyFile, "~") {
+		var err error
+		config.KeyFile, err = formatHomeDir(config.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if strings.HasPrefix(config.Age.Identity, "~") {
+		var err error
+		config.Age.Identity, err = formatHomeDir(config.Age.Identity)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if strings.HasPrefix(config.Kdbx.File, "~") {
+		var err error
+		config.Kdbx.File, err = formatHomeDir(config.Kdbx.File)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if config.Server.Directory == "" {
+		config.Server.Directory = path.Join(config.Directory, "server")
+	} else if strings.HasPrefix(config.Server.Directory, "~") {
+		var err error
+		config.Server.Directory, err = formatHomeDir(config.Server.Directory)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if strings.HasPrefix(config.Breach.Directory, "~") {
+		var err error
+		config.Breach.Directory, err = formatHomeDir(config.Breach.Directory)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if strings.HasPrefix(config.Sqlite.File, "~") {
+		var err error
+		config.Sqlite.File, err = formatHomeDir(config.Sqlite.File)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if strings.HasPrefix(config.Git.Directory, "~") {
+		var err error
+		config.Git.Directory, err = formatHomeDir(config.Git.Directory)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return &config, nil
 }
+
+// SetStorage changes the configured storage of the vault
+func SetStorage(vault, storage string) error {
+	return Update(func(config *Config) error {
+		if vault == MainVault {
+			config.Storage = storage
+			return nil
+		}
+
+		v, ok := config.Vaults[vault]
+		if !ok {
+			return errors.New("Unknown vault " + vault)
+		}
+
+		v.Storage = storage
+		config.Vaults[vault] = v
+		return nil
+	})
+}
+
+// Read returns the config file without the settings of the selected vault
+// and with unexpanded paths. Unlike Get it works if the selected vault does
+// not exist, Vault is set to its name nevertheless.
+func Read() (*Config, error) {
+	config := new()
+
+	file, err := ioutil.ReadFile(configFile)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(file, &config)
+	if err != nil {
+		return nil, err
+	}
+
+	config.Vault = config.selectedVaultName()
+	return &config, nil
+}
+
+// Update changes the config file, nothing is written if fn fails. Unlike Get
+// the paths are not expanded.
+func Update(fn func(*Config) error) error {
+	config, err := Read()
+	if err != nil {
+		return err
+	}
+
+	err = fn(config)
+	if err != nil {
+		return err
+	}
+
+	file, err := json.MarshalIndent(config, "", " ")
+	if err != nil {
+		return err
+	}
+
+	// The config holds secrets of the storages, files written by older
+	// versions were readable by everyone
+	err = os.Chmod(configFile, 0600)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(configFile, file, 0600)
+}
+
+// SelectVault changes the vault returned by Get for this run, an unknown
+// vault is reported by Get
+func SelectVault(name string) {
+	selectedVault = name
+}
+
+// selectedVaultName returns the name of the vault Get selects
+func (config *Config) selectedVaultName() string {
+	name := selectedVault
+	if name == "" {
+		name = config.DefaultVault
+	}
+	if name == "" {
+		name = MainVault
+	}
+
+	return name
+}
+
+// selectVault replaces the top level settings with the ones of the selected vault
+func (config *Config) selectVault() error {
+	name := config.selectedVaultName()
+	if name == MainVault {
+		config.Vault = MainVault
+		return nil
+	}
+
+	vault, ok := config.Vaults[name]
+	if !ok {
+		return errors.New("Unknown vault " + name)
+	}
+
+	config.Vault = name
+
+	if vault.Directory == "" {
+		config.Directory = path.Join(config.Directory, "vaults", name)
+	} else if strings.HasPrefix(vault.Directory, "~") {
+		var err error
+		config.Directory, err = formatHomeDir(vault.Directory)
+		if err != nil {
+			return err
+		}
+	} else {
+		config.Directory = vault.Directory
+	}
+
+	// The encryption and key file are not inherited, the key file of main
+	// could not be removed from a vault otherwise
+	config.Encryption = vault.Encryption
+	if config.Encryption == "" {
+		config.Encryption = "password"
+	}
+	config.KeyFile = vault.KeyFile
+
+	if vault.Storage != "" {
+		config.Storage = vault.Storage
+	}
+	if vault.Age != nil {
+		config.Age = *vault.Age
+	}
+	if vault.Kdbx != nil {
+		config.Kdbx = *vault.Kdbx
+	}
+	if vault.Firestore != nil {
+		config.Firestore = *vault.Firestore
+	}
+	if vault.Git != nil {
+		config.Git = *vault.Git
+	}
+	if vault.Sqlite != nil {
+		config.Sqlite = *vault.Sqlite
+	}
+	if vault.S3 != nil {
+		config.S3 = *vault.S3
+	}
+	if vault.WebDAV != nil {
+		config.WebDAV = *vault.WebDAV
+	}
+	if vault.Remote != nil {
+		config.Remote = *vault.Remote
+	}
+
+	return nil
+}
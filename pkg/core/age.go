@@ -0,0 +1,175 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"filippo.io/age"
+
+	"github.com/perryrh0dan/passline/pkg/crypt"
+	"github.com/perryrh0dan/passline/pkg/storage"
+)
+
+// CreateAgeIdentity generates the age identity file of the user if it does
+// not exist and returns its recipient
+func (c *Core) CreateAgeIdentity() (string, error) {
+	identities, err := c.readAgeIdentities()
+	if err == nil {
+		return identities[0].(*age.X25519Identity).Recipient().String(), nil
+	}
+
+	if !os.IsNotExist(err) {
+		return "", err
+	}
+
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		return "", err
+	}
+
+	err = os.MkdirAll(filepath.Dir(c.config.Age.Identity), 0700)
+	if err != nil {
+		return "", err
+	}
+
+	data := "# public key: " + identity.Recipient().String() + "\n" + identity.String() + "\n"
+	err = ioutil.WriteFile(c.config.Age.Identity, []byte(data), 0600)
+	if err != nil {
+		return "", err
+	}
+
+	return identity.Recipient().String(), nil
+}
+
+// InitAge creates the vault key of an empty age vault
+func (c *Core) InitAge(ctx context.Context, password []byte) error {
+	keyed, err := c.ageVault()
+	if err != nil {
+		return err
+	}
+
+	if _, err := keyed.Key(ctx); err == nil {
+		return errors.New("Age vault is already initialized")
+	}
+
+	items, err := keyed.GetAllItems(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(items) > 0 {
+		return errors.New("Age vault is not empty")
+	}
+
+	vaultKey, err := crypt.GenerateVaultKey()
+	if err != nil {
+		return err
+	}
+
+	return c.setAgeKey(ctx, keyed, vaultKey, password)
+}
+
+// UpdateAgeRecipients encrypts the vault key to the configured recipients
+func (c *Core) UpdateAgeRecipients(ctx context.Context, vaultKey []byte) error {
+	keyed, err := c.ageVault()
+	if err != nil {
+		return err
+	}
+
+	if c.PasswordRequired() {
+		return errors.New("No age recipients configured")
+	}
+
+	return c.setAgeKey(ctx, keyed, vaultKey, nil)
+}
+
+func (c *Core) setAgeKey(ctx context.Context, keyed *storage.Keyed, vaultKey, password []byte) error {
+	recipients := []age.Recipient{}
+	for _, r := range c.config.Age.Recipients {
+		recipient, err := age.ParseX25519Recipient(r)
+		if err != nil {
+			return err
+		}
+		recipients = append(recipients, recipient)
+	}
+
+	if len(recipients) == 0 {
+		recipient, err := age.NewScryptRecipient(string(password))
+		if err != nil {
+			return err
+		}
+		recipients = append(recipients, recipient)
+	}
+
+	encrypted, err := crypt.AgeEncrypt(vaultKey, recipients...)
+	if err != nil {
+		return err
+	}
+
+	return keyed.SetKey(ctx, storage.Credential{Username: "age", Password: base64.StdEncoding.EncodeToString(encrypted), RecoveryCodes: []string{}})
+}
+
+// unlockAge decrypts the vault key with the identity file or the password as
+// passphrase
+func (c *Core) unlockAge(ctx context.Context, password []byte) ([]byte, error) {
+	keyed, err := c.ageVault()
+	if err != nil {
+		return nil, err
+	}
+
+	item, err := keyed.Key(ctx)
+	if err != nil || len(item.Credentials) != 1 {
+		return nil, errors.New("Age vault has no key, run passline age init")
+	}
+
+	encrypted, err := base64.StdEncoding.DecodeString(item.Credentials[0].Password)
+	if err != nil {
+		return nil, err
+	}
+
+	identities := []age.Identity{}
+	if len(password) > 0 {
+		identity, err := age.NewScryptIdentity(string(password))
+		if err != nil {
+			return nil, err
+		}
+		identities = append(identities, identity)
+	}
+
+	fileIdentities, err := c.readAgeIdentities()
+	if err == nil {
+		identities = append(identities, fileIdentities...)
+	} else if len(identities) == 0 {
+		return nil, err
+	}
+
+	vaultKey, err := crypt.AgeDecrypt(encrypted, identities...)
+	if err != nil {
+		return nil, errors.New("Unable to decrypt the vault key: " + err.Error())
+	}
+
+	return vaultKey, nil
+}
+
+func (c *Core) readAgeIdentities() ([]age.Identity, error) {
+	data, err := ioutil.ReadFile(c.config.Age.Identity)
+	if err != nil {
+		return nil, err
+	}
+
+	return age.ParseIdentities(bytes.NewReader(data))
+}
+
+func (c *Core) ageVault() (*storage.Keyed, error) {
+	keyed, ok := c.storage.(*storage.Keyed)
+	if !ok || c.config.Encryption != "age" {
+		return nil, errors.New("Vault " + c.config.Vault + " is not an age vault, set its Encryption to age")
+	}
+
+	return keyed, nil
+}
@@ -0,0 +1,72 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/perryrh0dan/passline/pkg/config"
+)
+
+func TestAge(t *testing.T) {
+	ctx := context.Background()
+	c, _, cleanup := newTestCore(t, config.Config{Encryption: "age"})
+	defer cleanup()
+	c.config.Age.Identity = filepath.Join(c.config.Directory, "age.txt")
+
+	// Without recipients the password is the passphrase of the vault key
+	err := c.InitAge(ctx, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.InitAge(ctx, []byte("secret")); err == nil {
+		t.Errorf("InitAge() of an initialized vault succeeded")
+	}
+
+	key, err := c.UnlockKey(ctx, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.UnlockKey(ctx, []byte("wrong")); err == nil {
+		t.Errorf("UnlockKey() with wrong passphrase succeeded")
+	}
+
+	// Encrypted to the recipient of the identity file no password is needed
+	recipient, err := c.CreateAgeIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.config.Age.Recipients = []string{recipient}
+	err = c.UpdateAgeRecipients(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.PasswordRequired() {
+		t.Errorf("PasswordRequired() with recipients = true")
+	}
+
+	unlocked, err := c.UnlockKey(ctx, nil)
+	if err != nil || !bytes.Equal(unlocked, key) {
+		t.Errorf("UnlockKey() with the identity file = %v; wanted the vault key", err)
+	}
+
+	// The identity file does not match another recipient
+	c.config.Age.Identity = filepath.Join(c.config.Directory, "other.txt")
+	other, _ := c.CreateAgeIdentity()
+	c.config.Age.Identity = filepath.Join(c.config.Directory, "age.txt")
+
+	c.config.Age.Recipients = []string{other}
+	err = c.UpdateAgeRecipients(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.UnlockKey(ctx, nil); err == nil {
+		t.Errorf("UnlockKey() with an identity that is no recipient succeeded")
+	}
+}
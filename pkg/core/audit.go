@@ -0,0 +1,188 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/perryrh0dan/passline/pkg/crypt"
+	"github.com/perryrh0dan/passline/pkg/storage"
+)
+
+// Kinds of audit issues
+const (
+	IssueBreached        = "breached"
+	IssueReused          = "reused"
+	IssueWeak            = "weak"
+	IssueOld             = "old"
+	IssueNoRecoveryCodes = "no-recovery-codes"
+)
+
+// penalties are subtracted from the score of a credential for each issue
+var penalties = map[string]int{
+	IssueBreached:        60,
+	IssueReused:          40,
+	IssueWeak:            40,
+	IssueOld:             20,
+	IssueNoRecoveryCodes: 10,
+}
+
+// Issue is a problem found with a credential
+type Issue struct {
+	Item     string `json:"item"`
+	Username string `json:"username"`
+	Kind     string `json:"kind"`
+	Detail   string `json:"detail"`
+}
+
+// AuditReport is the health of the vault. Every credential starts with a
+// score of 100 that is lowered by its issues, the vault score is the average.
+type AuditReport struct {
+	Score       int            `json:"score"`
+	Credentials int            `json:"credentials"`
+	Counts      map[string]int `json:"counts"`
+	Issues      []Issue        `json:"issues"`
+	// Passwords were looked up in the breach dataset
+	BreachChecked bool `json:"breachChecked"`
+}
+
+// Audit decrypts all passwords in memory and reports breached, reused, weak
+// and old passwords and credentials without recovery codes. Breached
+// passwords are only reported if a breach dataset is configured.
+func (c *Core) Audit(ctx context.Context, globalPassword []byte, maxAge time.Duration) (AuditReport, error) {
+	items, err := c.storage.GetAllItems(ctx)
+	if err != nil {
+		return AuditReport{}, err
+	}
+
+	decrypted := []storage.Item{}
+	for _, item := range items {
+		if item.Name == storage.MembersItem || item.Name == storage.KeyItem {
+			continue
+		}
+
+		credentials := []storage.Credential{}
+		for _, credential := range item.Credentials {
+			err = c.DecryptPassword(&credential, globalPassword)
+			if err != nil {
+				return AuditReport{}, errors.New("Unable to decrypt " + item.Name + "/" + credential.Username)
+			}
+			credentials = append(credentials, credential)
+		}
+		item.Credentials = credentials
+		decrypted = append(decrypted, item)
+	}
+
+	var breaches map[string]int
+	if c.BreachCheck() {
+		breaches, err = c.breaches(decrypted, globalPassword)
+		if err != nil {
+			return AuditReport{}, err
+		}
+	}
+
+	return audit(decrypted, maxAge, storage.Now(), breaches), nil
+}
+
+// breaches maps the passwords of the items to how often they were breached
+func (c *Core) breaches(items []storage.Item, globalPassword []byte) (map[string]int, error) {
+	checker, err := c.breachChecker(globalPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	breaches := map[string]int{}
+	for _, item := range items {
+		for _, credential := range item.Credentials {
+			if _, ok := breaches[credential.Password]; ok {
+				continue
+			}
+
+			breaches[credential.Password], err = checker.Count(credential.Password)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return breaches, checker.Save()
+}
+
+// audit checks items with decrypted passwords, passwords of unknown age are
+// not reported as old. Breaches maps passwords to how often they were
+// breached, nil if they were not checked.
+func audit(items []storage.Item, maxAge time.Duration, now time.Time, breaches map[string]int) AuditReport {
+	report := AuditReport{Counts: map[string]int{}, Issues: []Issue{}, BreachChecked: breaches != nil}
+
+	// Group credentials by password to find reuse
+	users := map[string][]string{}
+	for _, item := range items {
+		for _, credential := range item.Credentials {
+			users[credential.Password] = append(users[credential.Password], item.Name+"/"+credential.Username)
+		}
+	}
+
+	total := 0
+	for _, item := range items {
+		for _, credential := range item.Credentials {
+			issues := []Issue{}
+			add := func(kind, detail string) {
+				issues = append(issues, Issue{Item: item.Name, Username: credential.Username, Kind: kind, Detail: detail})
+			}
+
+			if count := breaches[credential.Password]; count > 0 {
+				add(IssueBreached, "Seen "+strconv.Itoa(count)+" times in data breaches")
+			}
+
+			self := item.Name + "/" + credential.Username
+			if others := users[credential.Password]; len(others) > 1 {
+				shared := []string{}
+				for _, other := range others {
+					if other != self {
+						shared = append(shared, other)
+					}
+				}
+				add(IssueReused, "Also used by "+strings.Join(shared, ", "))
+			}
+
+			if bits, score := crypt.PasswordStrength(credential.Password); score < 3 {
+				add(IssueWeak, "Strength "+strconv.Itoa(score)+"/4, about "+strconv.Itoa(int(bits))+" bits")
+			}
+
+			if age := credential.PasswordAge(now); maxAge > 0 && age > maxAge {
+				add(IssueOld, "Changed "+strconv.Itoa(int(age.Hours()/24))+" days ago")
+			}
+
+			if len(credential.RecoveryCodes) == 0 {
+				add(IssueNoRecoveryCodes, "No recovery codes")
+			}
+
+			score := 100
+			for _, issue := range issues {
+				score -= penalties[issue.Kind]
+				report.Counts[issue.Kind]++
+			}
+			if score < 0 {
+				score = 0
+			}
+
+			total += score
+			report.Credentials++
+			report.Issues = append(report.Issues, issues...)
+		}
+	}
+
+	report.Score = 100
+	if report.Credentials > 0 {
+		report.Score = total / report.Credentials
+	}
+
+	sort.SliceStable(report.Issues, func(i, j int) bool {
+		return penalties[report.Issues[i].Kind] > penalties[report.Issues[j].Kind]
+	})
+
+	return report
+}
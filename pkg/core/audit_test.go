@@ -0,0 +1,51 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/perryrh0dan/passline/pkg/storage"
+)
+
+func TestAudit(t *testing.T) {
+	now := time.Date(2020, time.June, 1, 0, 0, 0, 0, time.UTC)
+	strong := "u4WXM56bZatFTS4-quqhlY"
+	codes := []string{"code"}
+
+	items := []storage.Item{
+		{Name: "github.com", Credentials: []storage.Credential{
+			{Username: "perry", Password: strong, RecoveryCodes: codes, Modified: now.AddDate(0, -1, 0)},
+		}},
+		{Name: "gitlab.com", Credentials: []storage.Credential{
+			{Username: "perry", Password: strong, RecoveryCodes: codes, Modified: now.AddDate(-2, 0, 0)},
+			{Username: "old", Password: "password1", RecoveryCodes: codes},
+		}},
+		{Name: "google.com", Credentials: []storage.Credential{
+			{Username: "perry", Password: "tK9#vQ2$mWx!Lp", Created: now},
+		}},
+	}
+
+	report := audit(items, 365*24*time.Hour, now, nil)
+
+	want := map[string]int{IssueReused: 2, IssueWeak: 1, IssueOld: 1, IssueNoRecoveryCodes: 1}
+	for kind, count := range want {
+		if report.Counts[kind] != count {
+			t.Errorf("audit() found %d %s issues; wanted %d", report.Counts[kind], kind, count)
+		}
+	}
+
+	// 60 + 40 + 60 + 90
+	if report.Credentials != 4 || report.Score != 62 {
+		t.Errorf("audit() scored %d credentials with %d; wanted 4 with 62", report.Credentials, report.Score)
+	}
+
+	// password1 is breached as well
+	report = audit(items, 365*24*time.Hour, now, map[string]int{"password1": 2418984, strong: 0})
+	if report.Counts[IssueBreached] != 1 || report.Issues[0].Kind != IssueBreached || report.Score != 47 {
+		t.Errorf("audit() with breaches found %d breached and scored %d; wanted 1 and 47", report.Counts[IssueBreached], report.Score)
+	}
+
+	if report := audit(nil, 0, now, nil); report.Score != 100 {
+		t.Errorf("audit() of an empty vault scored %d; wanted 100", report.Score)
+	}
+}
@@ -0,0 +1,191 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/perryrh0dan/passline/pkg/renderer"
+	"github.com/perryrh0dan/passline/pkg/storage"
+)
+
+const (
+	backupPrefix     = "passline-"
+	backupTimeFormat = "20060102-150405"
+	backupStateFile  = ".state.json"
+	// Backups of the same second are told apart by the nanoseconds, older
+	// backups without them are parsed with backupTimeFormat as well
+	backupFileFormat = backupTimeFormat + ".000000000"
+)
+
+type backupState struct {
+	Mutations int `json:"mutations"`
+}
+
+// ListBackups returns all backups in the backup directory, newest first
+func (c *Core) ListBackups() ([]storage.BackupFile, error) {
+	files, err := ioutil.ReadDir(c.config.Backup.Directory)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []storage.BackupFile{}, nil
+		}
+		return nil, err
+	}
+
+	backups := []storage.BackupFile{}
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() || !strings.HasPrefix(name, backupPrefix) || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+
+		stamp := strings.TrimSuffix(strings.TrimPrefix(name, backupPrefix), ".json")
+		date, err := time.ParseInLocation(backupTimeFormat, stamp, time.Local)
+		if err != nil {
+			continue
+		}
+
+		backups = append(backups, storage.BackupFile{
+			Path: filepath.Join(c.config.Backup.Directory, name),
+			Date: date,
+		})
+	}
+
+	sort.Slice(backups, func(i, j int) bool { return backups[i].Date.After(backups[j].Date) })
+	return backups, nil
+}
+
+// afterMutation runs the automatic backup and reports failures without
+// failing the mutation itself
+func (c *Core) afterMutation(ctx context.Context) {
+	err := c.autoBackup(ctx)
+	if err != nil {
+		renderer.AutoBackupError(err)
+	}
+}
+
+func (c *Core) autoBackup(ctx context.Context) error {
+	cfg := c.config.Backup
+	if cfg.Mutations <= 0 && cfg.MaxAge <= 0 {
+		return nil
+	}
+
+	err := os.MkdirAll(cfg.Directory, 0700)
+	if err != nil {
+		return err
+	}
+
+	state := c.getBackupState()
+	state.Mutations++
+
+	due := cfg.Mutations > 0 && state.Mutations >= cfg.Mutations
+	if !due && cfg.MaxAge > 0 {
+		backups, err := c.ListBackups()
+		if err != nil {
+			return err
+		}
+
+		maxAge := time.Duration(cfg.MaxAge) * 24 * time.Hour
+		due = len(backups) == 0 || time.Since(backups[0].Date) > maxAge
+	}
+
+	if due {
+		err = c.CreateBackup(ctx, c.backupPath())
+		if err != nil {
+			return err
+		}
+
+		state.Mutations = 0
+		err = c.pruneBackups()
+		if err != nil {
+			return err
+		}
+	}
+
+	return c.setBackupState(state)
+}
+
+// backupPath returns a file for a new backup that does not exist yet
+func (c *Core) backupPath() string {
+	date := time.Now()
+	for {
+		path := filepath.Join(c.config.Backup.Directory, backupPrefix+date.Format(backupFileFormat)+".json")
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return path
+		}
+		date = date.Add(time.Nanosecond)
+	}
+}
+
+func (c *Core) pruneBackups() error {
+	backups, err := c.ListBackups()
+	if err != nil {
+		return err
+	}
+
+	_, remove := retainBackups(backups, c.config.Backup.KeepLast, c.config.Backup.KeepMonthly)
+	for _, backup := range remove {
+		err := os.Remove(backup.Path)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// retainBackups splits backups sorted newest first into the ones to keep and
+// the ones to remove. The newest keepLast backups are kept as well as the
+// newest backup of each of the last keepMonthly months that have a backup.
+func retainBackups(backups []storage.BackupFile, keepLast, keepMonthly int) ([]storage.BackupFile, []storage.BackupFile) {
+	if keepLast <= 0 && keepMonthly <= 0 {
+		return backups, nil
+	}
+
+	keep := []storage.BackupFile{}
+	remove := []storage.BackupFile{}
+	months := map[string]bool{}
+
+	for i, backup := range backups {
+		month := backup.Date.Format("2006-01")
+		retained := i < keepLast
+
+		if !months[month] && len(months) < keepMonthly {
+			months[month] = true
+			retained = true
+		}
+
+		if retained {
+			keep = append(keep, backup)
+		} else {
+			remove = append(remove, backup)
+		}
+	}
+
+	return keep, remove
+}
+
+func (c *Core) getBackupState() backupState {
+	state := backupState{}
+
+	file, err := ioutil.ReadFile(filepath.Join(c.config.Backup.Directory, backupStateFile))
+	if err == nil {
+		_ = json.Unmarshal(file, &state)
+	}
+
+	return state
+}
+
+func (c *Core) setBackupState(state backupState) error {
+	file, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filepath.Join(c.config.Backup.Directory, backupStateFile), file, 0600)
+}
@@ -0,0 +1,55 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/perryrh0dan/passline/pkg/config"
+	"github.com/perryrh0dan/passline/pkg/storage"
+)
+
+func TestRetainBackups(t *testing.T) {
+	// Two backups per month, newest first
+	backups := []storage.BackupFile{}
+	start := time.Date(2019, time.December, 20, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 12; i++ {
+		date := start.AddDate(0, -i/2, -(i%2)*10)
+		backups = append(backups, storage.BackupFile{Path: date.String(), Date: date})
+	}
+
+	keep, remove := retainBackups(backups, 3, 4)
+
+	// 3 newest (Dec, Dec, Nov) plus newest of Oct and Sep
+	if len(keep) != 5 || len(remove) != 7 {
+		t.Errorf("retainBackups(3, 4) kept %d and removed %d; wanted 5 and 7", len(keep), len(remove))
+	}
+
+	keep, remove = retainBackups(backups, 0, 0)
+	if len(keep) != len(backups) || len(remove) != 0 {
+		t.Errorf("retainBackups(0, 0) kept %d; wanted all %d", len(keep), len(backups))
+	}
+}
+
+func TestAutoBackup(t *testing.T) {
+	ctx := context.Background()
+	c, _, cleanup := newTestCore(t, config.Config{Backup: config.Backup{Mutations: 1}})
+	defer cleanup()
+
+	// Backups of the same second do not replace each other
+	for i := 0; i < 3; i++ {
+		err := c.autoBackup(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	backups, err := c.ListBackups()
+	if err != nil || len(backups) != 3 {
+		t.Fatalf("ListBackups() = %d backups, %v; wanted 3", len(backups), err)
+	}
+
+	if !backups[0].Date.After(backups[2].Date) {
+		t.Errorf("ListBackups() returned %v before %v; wanted newest first", backups[0].Date, backups[2].Date)
+	}
+}
@@ -0,0 +1,99 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/perryrh0dan/passline/pkg/breach"
+)
+
+// breachCachePrefix names the files that keep the results of breach lookups
+// next to the storage of a vault
+const breachCachePrefix = "breaches-"
+
+// BreachedError is returned by AddItem and EditItem if breached passwords
+// are refused and the password was found in the breach dataset
+type BreachedError struct {
+	Count int
+}
+
+func (e *BreachedError) Error() string {
+	return "Password was seen " + strconv.Itoa(e.Count) + " times in data breaches"
+}
+
+// breachChecker returns the checker of the configured breach dataset, the
+// results are cached with hashes keyed by the vault key
+func (c *Core) breachChecker(globalPassword []byte) (*breach.Checker, error) {
+	checker, err := breach.New(c.config.Breach.Directory, c.config.Breach.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	// The directory of main also holds the named vaults, the cache is kept
+	// with the storage of the vault instead
+	storageDir := filepath.Join(c.config.Directory, "storage")
+	err = os.MkdirAll(storageDir, 0700)
+	if err != nil {
+		return nil, err
+	}
+
+	file := filepath.Join(storageDir, breachCachePrefix+c.config.Vault+".json")
+	err = checker.UseCache(file, globalPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	return checker, nil
+}
+
+// BreachCheck reports whether a breach dataset is configured
+func (c *Core) BreachCheck() bool {
+	return c.config.Breach.Directory != "" || c.config.Breach.URL != ""
+}
+
+// RefuseBreached reports whether breached passwords must not be added
+func (c *Core) RefuseBreached() bool {
+	return c.config.Breach.Refuse
+}
+
+// SetRefuseBreached refuses breached passwords for this run regardless of
+// the config
+func (c *Core) SetRefuseBreached() {
+	c.config.Breach.Refuse = true
+}
+
+// checkBreached returns a BreachedError for a breached password if breached
+// passwords are refused. The global password has to be verified before, it
+// keys the cache.
+func (c *Core) checkBreached(password string, globalPassword []byte) error {
+	if !c.config.Breach.Refuse {
+		return nil
+	}
+
+	count, err := c.Breached(password, globalPassword)
+	if err != nil {
+		return err
+	}
+
+	if count > 0 {
+		return &BreachedError{Count: count}
+	}
+
+	return nil
+}
+
+// Breached returns how often the password appears in the breach dataset
+func (c *Core) Breached(password string, globalPassword []byte) (int, error) {
+	checker, err := c.breachChecker(globalPassword)
+	if err != nil {
+		return 0, err
+	}
+
+	count, err := checker.Count(password)
+	if err != nil {
+		return 0, err
+	}
+
+	return count, checker.Save()
+}
@@ -0,0 +1,66 @@
+package core
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/perryrh0dan/passline/pkg/config"
+)
+
+func TestRefuseBreached(t *testing.T) {
+	ctx := context.Background()
+	key := []byte("12345678901234567890123456789012")
+
+	dataset, err := ioutil.TempDir("", "passline-breach")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dataset)
+
+	// SHA-1 of "password" is 5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8
+	err = ioutil.WriteFile(filepath.Join(dataset, "5BAA6.txt"), []byte("1E4C9B93F3F0682250B6CF8331B7EE68FD8:9659365\r\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, m, cleanup := newTestCore(t, config.Config{Breach: config.Breach{Directory: dataset, Refuse: true}})
+	defer cleanup()
+
+	// Generated passwords are not looked up
+	generated, err := c.GenerateItem(ctx, "github.com", "perry", []string{}, Details{}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writes := m.writes
+
+	_, err = c.AddItem(ctx, "gitlab.com", "perry", "password", []string{}, Details{}, key)
+	if refused, ok := err.(*BreachedError); !ok || refused.Count != 9659365 {
+		t.Errorf("AddItem() of a breached password = %v; wanted a BreachedError", err)
+	}
+
+	// The global password is verified before the lookup
+	_, err = c.AddItem(ctx, "gitlab.com", "perry", "password", []string{}, Details{}, []byte("00000000000000000000000000000000"))
+	if _, ok := err.(*BreachedError); ok || err == nil {
+		t.Errorf("AddItem() with wrong global password = %v; wanted the password error", err)
+	}
+
+	generated.Password = "password"
+	err = c.EditItem(ctx, "github.com", "perry", generated, Details{}, key)
+	if _, ok := err.(*BreachedError); !ok {
+		t.Errorf("EditItem() to a breached password = %v; wanted a BreachedError", err)
+	}
+
+	if m.writes != writes {
+		t.Errorf("Refused passwords were written %d times", m.writes-writes)
+	}
+
+	// Without refusing breached passwords are added
+	c.config.Breach.Refuse = false
+	_, err = c.AddItem(ctx, "gitlab.com", "perry", "password", []string{}, Details{}, key)
+	if err != nil {
+		t.Errorf("AddItem() without refusing = %v", err)
+	}
+}
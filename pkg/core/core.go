@@ -3,6 +3,7 @@ package core
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"io/ioutil"
 	"os"
 	"strings"
@@ -12,6 +13,7 @@ import (
 	"github.com/perryrh0dan/passline/pkg/crypt"
 	"github.com/perryrh0dan/passline/pkg/renderer"
 	"github.com/perryrh0dan/passline/pkg/storage"
+	"github.com/perryrh0dan/passline/pkg/util"
 )
 
 type Core struct {
@@ -21,23 +23,90 @@ type Core struct {
 
 func NewCore(ctx context.Context) (*Core, error) {
 	c := new(Core)
-	c.config, _ = config.Get()
+
 	var err error
-	switch c.config.Storage {
-	case "firestore":
-		c.storage, err = storage.NewFirestore(ctx)
+	c.config, err = config.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	name := c.config.Storage
+	if name == "" {
+		name = "local"
+	}
+
+	backend, err := storage.Lookup(name)
+	if err != nil {
+		return nil, err
+	}
+
+	c.storage, err = backend.New(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.config.OfflineCache && backend.Remote {
+		c.storage, err = storage.NewCache(c.storage, backend.Name)
 		if err != nil {
 			return nil, err
 		}
+	}
+
+	err = c.supports(backend.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	switch c.config.Encryption {
+	case "", "password":
+	case "team", "age":
+		c.storage = storage.NewKeyed(c.storage)
 	default:
-		c.storage, err = storage.NewLocalStorage()
-		if err != nil {
-			return nil, err
-		}
+		return nil, errors.New("Unknown encryption " + c.config.Encryption)
 	}
+
 	return c, nil
 }
 
+// supports reports an error if the storage can not hold the items of the
+// vault. A KeePass database stores the plaintext values and is protected by
+// the password and key file, so it can not hold a team or age vault key.
+func (c *Core) supports(name string) error {
+	if name == "kdbx" && c.config.Encryption != "" && c.config.Encryption != "password" {
+		return errors.New("Storage kdbx only supports password encryption")
+	}
+
+	return nil
+}
+
+// backend returns the storage below the team vault
+func (c *Core) backend() storage.Storage {
+	if team, ok := c.storage.(*storage.Keyed); ok {
+		return team.Unwrap()
+	}
+
+	return c.storage
+}
+
+// PasswordRequired is false for vaults that are unlocked with an age identity
+func (c *Core) PasswordRequired() bool {
+	return c.config.Encryption != "age" || len(c.config.Age.Recipients) == 0
+}
+
+// UnlockKey returns the key the items of the vault are encrypted with. The
+// vault key of team and age vaults is decrypted with the identity of the
+// user, otherwise it is the global password combined with the key file.
+func (c *Core) UnlockKey(ctx context.Context, password []byte) ([]byte, error) {
+	switch c.config.Encryption {
+	case "team":
+		return c.unlockTeam(ctx, password)
+	case "age":
+		return c.unlockAge(ctx, password)
+	default:
+		return c.compositeKey(password, c.config.KeyFile)
+	}
+}
+
 func (c *Core) CheckPassword(ctx context.Context, password []byte) (bool, error) {
 	data, err := c.storage.GetAllItems(ctx)
 	if err != nil {
@@ -62,8 +131,10 @@ func (c *Core) CheckPassword(ctx context.Context, password []byte) (bool, error)
 	return true, nil
 }
 
+// CreateBackup writes all items to path. The vault key items of team and
+// age vaults are included, the backup can not be decrypted without them.
 func (c *Core) CreateBackup(ctx context.Context, path string) error {
-	items, err := c.storage.GetAllItems(ctx)
+	items, err := c.backend().GetAllItems(ctx)
 	if err != nil {
 		return err
 	}
@@ -75,10 +146,12 @@ func (c *Core) CreateBackup(ctx context.Context, path string) error {
 	time := time.Now()
 	data := storage.Backup{Date: time, Items: items}
 
-	file, _ := json.MarshalIndent(data, "", " ")
-	_ = ioutil.WriteFile(path, file, 0644)
+	file, err := json.MarshalIndent(data, "", " ")
+	if err != nil {
+		return err
+	}
 
-	return nil
+	return ioutil.WriteFile(path, file, 0644)
 }
 
 func (c *Core) RestoreBackup(ctx context.Context, path string) error {
@@ -92,56 +165,252 @@ func (c *Core) RestoreBackup(ctx context.Context, path string) error {
 	file, _ := ioutil.ReadFile(path)
 	_ = json.Unmarshal([]byte(file), &data)
 
-	c.storage.SetData(ctx, data)
+	// The vault key items are restored as well, so the items are decrypted
+	// with the key of the backup and not the current one
+	err = c.backend().SetData(ctx, data)
+	if err != nil {
+		return err
+	}
 
+	c.afterMutation(ctx)
 	return nil
 }
 
-func (c *Core) AddItem(ctx context.Context, name, username, password string, recoveryCodes []string, globalPassword []byte) (storage.Credential, error) {
+// Sync synchronizes the storage with its remote
+func (c *Core) Sync(ctx context.Context) error {
+	syncer, ok := c.backend().(storage.Syncer)
+	if !ok {
+		return errors.New("Storage " + c.config.Storage + " does not support sync")
+	}
+
+	return syncer.Sync(ctx)
+}
+
+// Conflicts returns the changes that could not be synced because the items
+// were changed on the remote as well
+func (c *Core) Conflicts() []storage.Conflict {
+	cache, ok := c.backend().(*storage.Cache)
+	if !ok {
+		return nil
+	}
+
+	return cache.Conflicts()
+}
+
+func (c *Core) ResolveConflicts(ctx context.Context, keepLocal bool) error {
+	cache, ok := c.backend().(*storage.Cache)
+	if !ok {
+		return errors.New("Storage " + c.config.Storage + " has no offline cache")
+	}
+
+	return cache.ResolveConflicts(ctx, keepLocal)
+}
+
+// Details are the folder, tags and urls of an item. Nil fields keep the
+// values of an existing item.
+type Details struct {
+	Folder *string
+	Tags   []string
+	URIs   []storage.URI
+}
+
+func (d Details) empty() bool {
+	return d.Folder == nil && d.Tags == nil && d.URIs == nil
+}
+
+func (d Details) apply(item *storage.Item) {
+	if d.Folder != nil {
+		item.Folder = storage.CleanFolder(*d.Folder)
+	}
+	if d.Tags != nil {
+		item.Tags = storage.CleanTags(d.Tags)
+	}
+	if d.URIs != nil {
+		item.URIs = d.URIs
+	}
+}
 
+// AddItem adds a credential and the details of the item with one write. A
+// breached password is refused with a BreachedError if configured.
+func (c *Core) AddItem(ctx context.Context, name, username, password string, recoveryCodes []string, details Details, globalPassword []byte) (storage.Credential, error) {
+	return c.addItem(ctx, name, username, password, recoveryCodes, details, globalPassword, true)
+}
+
+func (c *Core) addItem(ctx context.Context, name, username, password string, recoveryCodes []string, details Details, globalPassword []byte, checkBreach bool) (storage.Credential, error) {
 	// Check global password.
 	valid, err := c.CheckPassword(ctx, globalPassword)
 	if err != nil || !valid {
 		return storage.Credential{}, err
 	}
 
+	if checkBreach {
+		err = c.checkBreached(password, globalPassword)
+		if err != nil {
+			return storage.Credential{}, err
+		}
+	}
+
 	// Create Credentials
-	credential := storage.Credential{Username: username, Password: password, RecoveryCodes: recoveryCodes}
+	now := storage.Now()
+	credential := storage.Credential{Username: username, Password: password, RecoveryCodes: recoveryCodes, Created: now, Modified: now}
 
 	err = c.EncryptCredential(&credential, globalPassword)
 	if err != nil {
 		return storage.Credential{}, err
 	}
 
-	err = c.addCredential(ctx, name, credential)
+	err = c.addCredential(ctx, name, credential, details)
 	if err != nil {
 		return storage.Credential{}, err
 	}
 
+	c.afterMutation(ctx)
+
 	credential.Password = password
 	return credential, nil
 }
 
-func (c *Core) addCredential(ctx context.Context, name string, credential storage.Credential) error {
-	// Check if item already exists
-	_, err := c.storage.GetItemByName(ctx, name)
+// ImportItems adds the plaintext credentials of all items. Credentials whose
+// name and username combination already exists are skipped and returned.
+// Every changed item is written once.
+func (c *Core) ImportItems(ctx context.Context, items []storage.Item, globalPassword []byte) (int, []string, error) {
+	// Check global password.
+	valid, err := c.CheckPassword(ctx, globalPassword)
+	if err != nil || !valid {
+		return 0, nil, err
+	}
+
+	existing, err := c.storage.GetAllItems(ctx)
 	if err != nil {
-		// Generate new item entry
-		item := storage.Item{Name: name, Credentials: []storage.Credential{credential}}
-		err = c.storage.CreateItem(ctx, item)
+		return 0, nil, err
+	}
+
+	stored := map[string]bool{}
+	merged := map[string]*storage.Item{}
+	for i := range existing {
+		stored[existing[i].Name] = true
+		merged[existing[i].Name] = &existing[i]
+	}
+
+	imported := 0
+	skipped := []string{}
+	changed := []string{}
+	now := storage.Now()
+
+	for _, item := range items {
+		target, ok := merged[item.Name]
+		if !ok {
+			target = &storage.Item{Name: item.Name, Credentials: []storage.Credential{}}
+			merged[item.Name] = target
+		}
+
+		modified := false
+		for _, credential := range item.Credentials {
+			if _, err := target.GetCredentialByUsername(credential.Username); err == nil {
+				skipped = append(skipped, item.Name+"/"+credential.Username)
+				continue
+			}
+
+			if credential.Created.IsZero() {
+				credential.Created = now
+			}
+			if credential.Modified.IsZero() {
+				credential.Modified = credential.Created
+			}
+
+			err = c.EncryptCredential(&credential, globalPassword)
+			if err != nil {
+				return 0, nil, err
+			}
+
+			target.Credentials = append(target.Credentials, credential)
+			imported++
+			modified = true
+		}
+
+		// Details missing in the import do not clear the stored ones
+		if folder := storage.CleanFolder(item.Folder); folder != "" {
+			target.Folder = folder
+			modified = true
+		}
+
+		if len(item.Tags) > 0 {
+			target.Tags = storage.CleanTags(append(target.Tags, item.Tags...))
+			modified = true
+		}
+
+		if len(item.URIs) > 0 {
+			target.URIs = item.URIs
+			modified = true
+		}
+
+		if modified && len(target.Credentials) > 0 && !util.ArrayContains(changed, item.Name) {
+			changed = append(changed, item.Name)
+		}
+	}
+
+	for _, name := range changed {
+		var err error
+		if stored[name] {
+			err = c.storage.UpdateItem(ctx, *merged[name])
+		} else {
+			err = c.storage.CreateItem(ctx, *merged[name])
+		}
 		if err != nil {
-			os.Exit(0)
+			return 0, nil, err
 		}
-	} else {
-		// TODO check if credential already exists
-		// Add to existing item
-		err := c.storage.AddCredential(ctx, name, credential)
+	}
+
+	if len(changed) > 0 {
+		c.afterMutation(ctx)
+	}
+
+	return imported, skipped, nil
+}
+
+func (c *Core) addCredential(ctx context.Context, name string, credential storage.Credential, details Details) error {
+	item, err := c.storage.GetItemByName(ctx, name)
+	if err != nil {
+		// Generate new item entry
+		item = storage.Item{Name: name, Credentials: []storage.Credential{credential}}
+		details.apply(&item)
+		return c.storage.CreateItem(ctx, item)
+	}
+
+	// TODO check if credential already exists
+	if details.empty() {
+		return c.storage.AddCredential(ctx, name, credential)
+	}
+
+	item.Credentials = append(item.Credentials, credential)
+	details.apply(&item)
+	return c.storage.UpdateItem(ctx, item)
+}
+
+// ExportItems returns the items with the given names with decrypted credentials
+func (c *Core) ExportItems(ctx context.Context, names []string, globalPassword []byte) ([]storage.Item, error) {
+	items := []storage.Item{}
+
+	for _, name := range names {
+		item, err := c.storage.GetItemByName(ctx, name)
 		if err != nil {
-			os.Exit(0)
+			return nil, err
 		}
+
+		credentials := []storage.Credential{}
+		for _, credential := range item.Credentials {
+			err = c.DecryptCredential(&credential, globalPassword)
+			if err != nil {
+				return nil, err
+			}
+			credentials = append(credentials, credential)
+		}
+
+		item.Credentials = credentials
+		items = append(items, item)
 	}
 
-	return nil
+	return items, nil
 }
 
 func (c *Core) DecryptCredential(credential *storage.Credential, globalPassword []byte) error {
@@ -222,14 +491,15 @@ func (c *Core) EncryptRecoveryCodes(credential *storage.Credential, globalPasswo
 	return nil
 }
 
-func (c *Core) GenerateItem(ctx context.Context, name, username string, recoveryCodes []string, globalPassword []byte) (storage.Credential, error) {
+func (c *Core) GenerateItem(ctx context.Context, name, username string, recoveryCodes []string, details Details, globalPassword []byte) (storage.Credential, error) {
 	// Generate password and crypt password
 	password, err := crypt.GeneratePassword(20)
 	if err != nil {
 		return storage.Credential{}, err
 	}
 
-	return c.AddItem(ctx, name, username, password, recoveryCodes, globalPassword)
+	// Generated passwords are not looked up
+	return c.addItem(ctx, name, username, password, recoveryCodes, details, globalPassword, false)
 }
 
 func (c *Core) DeleteItem(ctx context.Context, name, username string) error {
@@ -248,10 +518,13 @@ func (c *Core) DeleteItem(ctx context.Context, name, username string) error {
 		os.Exit(0)
 	}
 
+	c.afterMutation(ctx)
 	return nil
 }
 
-func (c *Core) EditItem(ctx context.Context, name, username string, updatedCredential storage.Credential, globalPassword []byte) error {
+// EditItem replaces a credential and the details of the item with one write.
+// A new breached password is refused with a BreachedError if configured.
+func (c *Core) EditItem(ctx context.Context, name, username string, updatedCredential storage.Credential, details Details, globalPassword []byte) error {
 	item, err := c.storage.GetItemByName(ctx, name)
 	if err != nil {
 		return err
@@ -267,6 +540,25 @@ func (c *Core) EditItem(ctx context.Context, name, username string, updatedCrede
 		}
 	}
 
+	// The password age only changes with the password
+	previous := *credential
+	err = c.DecryptPassword(&previous, globalPassword)
+	if err != nil {
+		return err
+	}
+
+	updatedCredential.Created = previous.Created
+	updatedCredential.Modified = previous.Modified
+	if updatedCredential.Password != previous.Password {
+		updatedCredential.Modified = storage.Now()
+
+		// The global password is verified by the decryption above
+		err = c.checkBreached(updatedCredential.Password, globalPassword)
+		if err != nil {
+			return err
+		}
+	}
+
 	*credential = updatedCredential
 
 	err = c.EncryptCredential(credential, globalPassword)
@@ -274,14 +566,34 @@ func (c *Core) EditItem(ctx context.Context, name, username string, updatedCrede
 		return err
 	}
 
+	details.apply(&item)
+
 	err = c.storage.UpdateItem(ctx, item)
 	if err != nil {
 		return err
 	}
 
+	c.afterMutation(ctx)
 	return nil
 }
 
+// FindURL returns the items with an uri matching the url
+func (c *Core) FindURL(ctx context.Context, url string) ([]storage.Item, error) {
+	items, err := c.storage.GetAllItems(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := []storage.Item{}
+	for _, item := range items {
+		if item.MatchesURL(url) {
+			matches = append(matches, item)
+		}
+	}
+
+	return matches, nil
+}
+
 func (c *Core) GetSites(ctx context.Context) ([]storage.Item, error) {
 	items, err := c.storage.GetAllItems(ctx)
 	if err != nil {
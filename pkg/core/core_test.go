@@ -0,0 +1,236 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"sort"
+	"testing"
+
+	"github.com/perryrh0dan/passline/pkg/config"
+	"github.com/perryrh0dan/passline/pkg/storage"
+)
+
+// memory keeps the items in memory and counts the writes
+type memory struct {
+	items  []storage.Item
+	writes int
+}
+
+func (m *memory) GetItemByName(ctx context.Context, name string) (storage.Item, error) {
+	for _, item := range m.items {
+		if item.Name == name {
+			return copyItem(item), nil
+		}
+	}
+
+	return storage.Item{}, errors.New("Item not found")
+}
+
+func (m *memory) GetItemByIndex(ctx context.Context, index int) (storage.Item, error) {
+	items, _ := m.GetAllItems(ctx)
+	if index < 0 || index >= len(items) {
+		return storage.Item{}, errors.New("Out of index")
+	}
+
+	return items[index], nil
+}
+
+func (m *memory) GetAllItems(ctx context.Context) ([]storage.Item, error) {
+	items := []storage.Item{}
+	for _, item := range m.items {
+		items = append(items, copyItem(item))
+	}
+
+	sort.Sort(storage.ByName(items))
+	return items, nil
+}
+
+func (m *memory) CreateItem(ctx context.Context, item storage.Item) error {
+	m.writes++
+	m.items = append(m.items, copyItem(item))
+	return nil
+}
+
+func (m *memory) AddCredential(ctx context.Context, name string, credential storage.Credential) error {
+	m.writes++
+	for i := range m.items {
+		if m.items[i].Name == name {
+			m.items[i].Credentials = append(m.items[i].Credentials, credential)
+			return nil
+		}
+	}
+
+	return errors.New("Item not found")
+}
+
+func (m *memory) DeleteCredential(ctx context.Context, item storage.Item, credential storage.Credential) error {
+	m.writes++
+	for i := range m.items {
+		if m.items[i].Name != item.Name {
+			continue
+		}
+
+		credentials := []storage.Credential{}
+		for _, c := range m.items[i].Credentials {
+			if c.Username != credential.Username {
+				credentials = append(credentials, c)
+			}
+		}
+		m.items[i].Credentials = credentials
+
+		if len(credentials) == 0 {
+			m.items = append(m.items[:i], m.items[i+1:]...)
+		}
+		return nil
+	}
+
+	return errors.New("Item not found")
+}
+
+func (m *memory) UpdateItem(ctx context.Context, item storage.Item) error {
+	m.writes++
+	for i := range m.items {
+		if m.items[i].Name == item.Name {
+			m.items[i] = copyItem(item)
+			return nil
+		}
+	}
+
+	return errors.New("Item not found")
+}
+
+func (m *memory) SetData(ctx context.Context, data storage.Data) error {
+	m.writes++
+	m.items = []storage.Item{}
+	for _, item := range data.Items {
+		m.items = append(m.items, copyItem(item))
+	}
+	return nil
+}
+
+func copyItem(item storage.Item) storage.Item {
+	item.Credentials = append([]storage.Credential{}, item.Credentials...)
+	item.Tags = append([]string{}, item.Tags...)
+	item.URIs = append([]storage.URI{}, item.URIs...)
+	return item
+}
+
+// newTestCore returns a core with a memory storage and a temporary directory
+// that is removed by the returned function
+func newTestCore(t *testing.T, cfg config.Config) (*Core, *memory, func()) {
+	dir, err := ioutil.TempDir("", "passline-core")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Directory == "" {
+		cfg.Directory = dir
+	}
+	if cfg.Backup.Directory == "" {
+		cfg.Backup.Directory = dir + "/backups"
+	}
+
+	m := &memory{}
+	c := &Core{config: &cfg, storage: m}
+	if cfg.Encryption == "team" || cfg.Encryption == "age" {
+		c.storage = storage.NewKeyed(m)
+	}
+
+	return c, m, func() { os.RemoveAll(dir) }
+}
+
+func TestImportItems(t *testing.T) {
+	ctx := context.Background()
+	c, m, cleanup := newTestCore(t, config.Config{Backup: config.Backup{Mutations: 10}})
+	defer cleanup()
+	key := []byte("12345678901234567890123456789012")
+
+	_, err := c.AddItem(ctx, "github.com", "perry", "secret", []string{}, Details{}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.writes = 0
+
+	items := []storage.Item{
+		{Name: "github.com", Folder: "dev", Credentials: []storage.Credential{{Username: "perry", Password: "other"}, {Username: "bot", Password: "token"}}},
+		{Name: "gitlab.com", Tags: []string{"work"}, URIs: []storage.URI{{URI: "gitlab.com"}}, Credentials: []storage.Credential{{Username: "perry", Password: "pass"}}},
+		{Name: "gitlab.com", Folder: "dev", Tags: []string{"oss"}, Credentials: []storage.Credential{{Username: "perry", Password: "again"}, {Username: "ci", Password: "ci"}}},
+	}
+
+	imported, skipped, err := c.ImportItems(ctx, items, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if imported != 3 || len(skipped) != 2 {
+		t.Errorf("ImportItems() imported %d and skipped %v; wanted 3 and 2", imported, skipped)
+	}
+
+	// One write per item
+	if m.writes != 2 {
+		t.Errorf("ImportItems() wrote %d times; wanted 2", m.writes)
+	}
+
+	gitlab, _ := m.GetItemByName(ctx, "gitlab.com")
+	// The details of both entries are merged
+	if len(gitlab.Credentials) != 2 || !gitlab.HasTag("work") || !gitlab.HasTag("oss") || gitlab.Folder != "dev" || len(gitlab.URIs) != 1 {
+		t.Errorf("ImportItems() stored %+v", gitlab)
+	}
+
+	github, _ := m.GetItemByName(ctx, "github.com")
+	credential, _ := github.GetCredentialByUsername("perry")
+	if err := c.DecryptPassword(&credential, key); err != nil || credential.Password != "secret" || github.Folder != "dev" {
+		t.Errorf("ImportItems() changed the existing credential or missed the folder: %+v", github)
+	}
+
+	// The import counts as a single mutation for the automatic backup
+	if state := c.getBackupState(); state.Mutations != 2 {
+		t.Errorf("ImportItems() counted %d mutations in total; wanted 2", state.Mutations)
+	}
+}
+
+func TestAddItemDetails(t *testing.T) {
+	ctx := context.Background()
+	c, m, cleanup := newTestCore(t, config.Config{})
+	defer cleanup()
+	key := []byte("12345678901234567890123456789012")
+
+	folder := "dev/code"
+	details := Details{Folder: &folder, Tags: []string{"work", " "}, URIs: []storage.URI{{URI: "github.com"}}}
+	_, err := c.AddItem(ctx, "github.com", "perry", "secret", []string{}, details, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Unset details keep the values of the item
+	_, err = c.AddItem(ctx, "github.com", "bot", "token", []string{}, Details{Tags: []string{"ci"}}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if m.writes != 2 {
+		t.Errorf("AddItem() wrote %d times for two credentials; wanted 2", m.writes)
+	}
+
+	item, _ := m.GetItemByName(ctx, "github.com")
+	if len(item.Credentials) != 2 || item.Folder != folder || len(item.Tags) != 1 || !item.HasTag("ci") || len(item.URIs) != 1 {
+		t.Errorf("AddItem() stored %+v", item)
+	}
+
+	credential, _ := item.GetCredentialByUsername("perry")
+	_ = c.DecryptCredential(&credential, key)
+	credential.Password = "changed"
+
+	empty := ""
+	err = c.EditItem(ctx, "github.com", "perry", credential, Details{Folder: &empty, URIs: []storage.URI{}}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	item, _ = m.GetItemByName(ctx, "github.com")
+	if m.writes != 3 || item.Folder != "" || len(item.URIs) != 0 || !item.HasTag("ci") {
+		t.Errorf("EditItem() wrote %d times and stored %+v; wanted one write", m.writes-2, item)
+	}
+}
@@ -0,0 +1,157 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/perryrh0dan/passline/pkg/config"
+	"github.com/perryrh0dan/passline/pkg/crypt"
+	"github.com/perryrh0dan/passline/pkg/storage"
+)
+
+// GenerateKeyFile writes a new key file, existing files are not replaced
+func (c *Core) GenerateKeyFile(path string) error {
+	_, err := os.Stat(path)
+	if err == nil {
+		return errors.New("Key file " + path + " already exists")
+	}
+
+	data, err := crypt.GenerateKeyFile()
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, data, 0400)
+}
+
+// ChangeKeyFile encrypts all items with the global password and the new key
+// file and configures it for the vault. An empty path removes the key file.
+// A backup is created first and restored if the config can not be changed,
+// the items would not be readable with the configured key file otherwise.
+func (c *Core) ChangeKeyFile(ctx context.Context, path string, password []byte) error {
+	if c.config.Encryption != "" && c.config.Encryption != "password" {
+		return errors.New("Key files are only supported with password encryption")
+	}
+
+	// The key file also protects the database itself
+	if c.config.Storage == "kdbx" {
+		return errors.New("The key file of a kdbx database can not be changed")
+	}
+
+	oldKey, err := c.UnlockKey(ctx, password)
+	if err != nil {
+		return err
+	}
+
+	valid, err := c.CheckPassword(ctx, oldKey)
+	if err != nil || !valid {
+		return errors.New("Invalid password")
+	}
+
+	if path != "" {
+		path, err = filepath.Abs(path)
+		if err != nil {
+			return err
+		}
+	}
+
+	newKey, err := c.compositeKey(password, path)
+	if err != nil {
+		return err
+	}
+
+	backup := c.backupPath()
+	err = os.MkdirAll(filepath.Dir(backup), 0700)
+	if err != nil {
+		return err
+	}
+
+	err = c.CreateBackup(ctx, backup)
+	if err != nil {
+		return err
+	}
+
+	items, err := c.storage.GetAllItems(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = c.reencrypt(items, oldKey, newKey)
+	if err != nil {
+		return err
+	}
+
+	err = c.storage.SetData(ctx, storage.Data{Items: items})
+	if err != nil {
+		return err
+	}
+
+	err = config.Update(func(cfg *config.Config) error {
+		if c.config.Vault == config.MainVault {
+			cfg.KeyFile = path
+			return nil
+		}
+
+		vault, ok := cfg.Vaults[c.config.Vault]
+		if !ok {
+			return errors.New("Unknown vault " + c.config.Vault)
+		}
+
+		vault.KeyFile = path
+		cfg.Vaults[c.config.Vault] = vault
+		return nil
+	})
+	if err != nil {
+		restoreErr := c.RestoreBackup(ctx, backup)
+		if restoreErr != nil {
+			return errors.New(err.Error() + ", restoring the backup " + backup + " failed: " + restoreErr.Error())
+		}
+		return err
+	}
+
+	c.config.KeyFile = path
+	return nil
+}
+
+// compositeKey combines the password with the content of the key file
+func (c *Core) compositeKey(password []byte, keyFile string) ([]byte, error) {
+	if keyFile == "" {
+		return password, nil
+	}
+
+	data, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return nil, errors.New("Unable to read key file " + keyFile)
+	}
+
+	return crypt.CompositeKey(password, data), nil
+}
+
+// reencrypt replaces the encryption key of all credentials
+func (c *Core) reencrypt(items []storage.Item, oldKey, newKey []byte) error {
+	for i := range items {
+		for j := range items[i].Credentials {
+			credential := &items[i].Credentials[j]
+
+			err := c.DecryptCredential(credential, oldKey)
+			if err != nil {
+				return err
+			}
+
+			err = c.EncryptCredential(credential, newKey)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,122 @@
+package core
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/perryrh0dan/passline/pkg/config"
+)
+
+// newKeyFileCore returns a core of the vault with a new memory storage that
+// holds one item encrypted with password
+func newKeyFileCore(t *testing.T, vault string, password []byte) *Core {
+	config.SelectVault(vault)
+	cfg, err := config.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := &Core{config: cfg, storage: &memory{}}
+	_, err = c.AddItem(context.Background(), "github.com", "perry", "secret", []string{}, Details{}, password)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return c
+}
+
+// decrypts reports if the item of c is decrypted with the key of password
+func decrypts(c *Core, password []byte) bool {
+	key, err := c.UnlockKey(context.Background(), password)
+	if err != nil {
+		return false
+	}
+
+	item, _ := c.GetSite(context.Background(), "github.com")
+	credential := item.Credentials[0]
+	return c.DecryptPassword(&credential, key) == nil && credential.Password == "secret"
+}
+
+func TestChangeKeyFile(t *testing.T) {
+	ctx := context.Background()
+	password := []byte("password")
+
+	dir, err := ioutil.TempDir("", "passline-keyfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer config.SelectVault("")
+
+	file := filepath.Join(dir, "config.json")
+	data := `{"Directory": "` + dir + `", "Storage": "local", "Vaults": {"work": {}}}`
+	err = ioutil.WriteFile(file, []byte(data), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.UseFile(file)
+
+	mainKeyFile := filepath.Join(dir, "main.key")
+	workKeyFile := filepath.Join(dir, "work.key")
+	mainVault := newKeyFileCore(t, config.MainVault, password)
+	_ = mainVault.GenerateKeyFile(mainKeyFile)
+	_ = mainVault.GenerateKeyFile(workKeyFile)
+
+	err = mainVault.ChangeKeyFile(ctx, mainKeyFile, password)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !decrypts(mainVault, password) {
+		t.Errorf("ChangeKeyFile() of main did not re-encrypt the items")
+	}
+
+	// The key file of main is not used by other vaults
+	workVault := newKeyFileCore(t, "work", password)
+	if workVault.config.KeyFile != "" {
+		t.Errorf("Vault work uses the key file %s of main", workVault.config.KeyFile)
+	}
+
+	err = workVault.ChangeKeyFile(ctx, workKeyFile, password)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, _ := config.Read()
+	if cfg.KeyFile != mainKeyFile || cfg.Vaults["work"].KeyFile != workKeyFile {
+		t.Errorf("ChangeKeyFile() configured %s for main and %s for work", cfg.KeyFile, cfg.Vaults["work"].KeyFile)
+	}
+
+	// Without path the key file of a vault is removed
+	err = workVault.ChangeKeyFile(ctx, "", password)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, _ = config.Get()
+	if cfg.KeyFile != "" || !decrypts(workVault, password) {
+		t.Errorf("ChangeKeyFile() without path kept the key file %s", cfg.KeyFile)
+	}
+
+	plain := &Core{config: &config.Config{}, storage: mainVault.storage}
+	if decrypts(plain, password) {
+		t.Errorf("The items of main are decrypted without its key file")
+	}
+
+	// The items are restored if the key file can not be configured
+	err = ioutil.WriteFile(file, []byte(`{"Directory": "`+dir+`", "Storage": "local"}`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := workVault.ChangeKeyFile(ctx, workKeyFile, password); err == nil {
+		t.Errorf("ChangeKeyFile() of a removed vault succeeded")
+	}
+
+	if workVault.config.KeyFile != "" || !decrypts(workVault, password) {
+		t.Errorf("ChangeKeyFile() did not restore the items after the config could not be changed")
+	}
+}
@@ -0,0 +1,151 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"reflect"
+
+	"github.com/perryrh0dan/passline/pkg/config"
+	"github.com/perryrh0dan/passline/pkg/crypt"
+	"github.com/perryrh0dan/passline/pkg/storage"
+)
+
+// Migrate copies all items from one storage to another, verifies that every
+// credential decrypts to the same values from the new storage and switches
+// the configured storage of the vault. It returns the number of migrated
+// items. An empty source is the configured storage.
+func (c *Core) Migrate(ctx context.Context, from, to string, globalPassword []byte) (int, error) {
+	if from == "" {
+		from = c.config.Storage
+	}
+
+	if from == to {
+		return 0, errors.New("Source and destination storage are the same")
+	}
+
+	err := c.supports(to)
+	if err != nil {
+		return 0, err
+	}
+
+	source, err := openStorage(ctx, from)
+	if err != nil {
+		return 0, err
+	}
+
+	destination, err := openStorage(ctx, to)
+	if err != nil {
+		return 0, err
+	}
+
+	count, err := migrate(ctx, source, destination, globalPassword)
+	if err != nil {
+		return 0, errors.New("Migration to storage " + to + " failed: " + err.Error())
+	}
+
+	err = config.SetStorage(c.config.Vault, to)
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// migrate copies the items into the empty destination and reads them back.
+// If they differ the destination is emptied again, so a failed migration
+// leaves no partial copy behind.
+func migrate(ctx context.Context, source, destination storage.Storage, globalPassword []byte) (int, error) {
+	items, err := source.GetAllItems(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	expected, err := decryptAll(items, globalPassword)
+	if err != nil {
+		return 0, err
+	}
+
+	existing, err := destination.GetAllItems(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(existing) > 0 {
+		return 0, errors.New("Storage is not empty")
+	}
+
+	err = destination.SetData(ctx, storage.Data{Items: items})
+	if err != nil {
+		return 0, rollback(ctx, destination, err)
+	}
+
+	migrated, err := destination.GetAllItems(ctx)
+	if err != nil {
+		return 0, rollback(ctx, destination, err)
+	}
+
+	if len(migrated) != len(items) {
+		return 0, rollback(ctx, destination, errors.New("Storage contains a different number of items after the migration"))
+	}
+
+	actual, err := decryptAll(migrated, globalPassword)
+	if err != nil {
+		return 0, rollback(ctx, destination, err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		return 0, rollback(ctx, destination, errors.New("Items differ from the source after the migration"))
+	}
+
+	return len(items), nil
+}
+
+// rollback empties the destination of a failed migration
+func rollback(ctx context.Context, destination storage.Storage, cause error) error {
+	err := destination.SetData(ctx, storage.Data{Items: []storage.Item{}})
+	if err != nil {
+		return errors.New(cause.Error() + ", removing the copied items failed: " + err.Error())
+	}
+
+	return cause
+}
+
+func openStorage(ctx context.Context, name string) (storage.Storage, error) {
+	backend, err := storage.Lookup(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return backend.New(ctx)
+}
+
+// decryptAll maps item and username to the decrypted password and recovery codes
+func decryptAll(items []storage.Item, globalPassword []byte) (map[string][]string, error) {
+	decrypted := map[string][]string{}
+
+	for _, item := range items {
+		if item.Name == storage.MembersItem || item.Name == storage.KeyItem {
+			continue
+		}
+
+		for _, credential := range item.Credentials {
+			password, err := crypt.AesGcmDecrypt(globalPassword, credential.Password)
+			if err != nil {
+				return nil, errors.New("Unable to decrypt " + item.Name + "/" + credential.Username)
+			}
+
+			values := []string{password}
+			for _, code := range credential.RecoveryCodes {
+				value, err := crypt.AesGcmDecrypt(globalPassword, code)
+				if err != nil {
+					return nil, errors.New("Unable to decrypt " + item.Name + "/" + credential.Username)
+				}
+				values = append(values, value)
+			}
+
+			decrypted[item.Name+"/"+credential.Username] = values
+		}
+	}
+
+	return decrypted, nil
+}
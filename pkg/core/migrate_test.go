@@ -0,0 +1,116 @@
+package core
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/perryrh0dan/passline/pkg/config"
+	"github.com/perryrh0dan/passline/pkg/crypt"
+	"github.com/perryrh0dan/passline/pkg/storage"
+)
+
+// lossy drops the recovery codes of the items it stores
+type lossy struct {
+	*memory
+}
+
+func (l *lossy) SetData(ctx context.Context, data storage.Data) error {
+	items := []storage.Item{}
+	for _, item := range data.Items {
+		item = copyItem(item)
+		for i := range item.Credentials {
+			item.Credentials[i].RecoveryCodes = []string{}
+		}
+		items = append(items, item)
+	}
+
+	return l.memory.SetData(ctx, storage.Data{Items: items})
+}
+
+func TestMigrate(t *testing.T) {
+	ctx := context.Background()
+	key := []byte("12345678901234567890123456789012")
+
+	password, _ := crypt.AesGcmEncrypt(key, "secret")
+	code, _ := crypt.AesGcmEncrypt(key, "code")
+	source := &memory{items: []storage.Item{
+		{Name: "github.com", Credentials: []storage.Credential{{Username: "perry", Password: password, RecoveryCodes: []string{code}}}},
+		{Name: "gitlab.com", Credentials: []storage.Credential{{Username: "perry", Password: password, RecoveryCodes: []string{}}}},
+	}}
+
+	destination := &memory{}
+	count, err := migrate(ctx, source, destination, key)
+	if err != nil || count != 2 || len(destination.items) != 2 {
+		t.Fatalf("migrate() = %d, %v; wanted 2 items in the destination", count, err)
+	}
+
+	// The destination must be empty
+	if _, err := migrate(ctx, source, destination, key); err == nil {
+		t.Errorf("migrate() into a storage with items succeeded")
+	}
+
+	// Items that can not be decrypted are not copied
+	if _, err := migrate(ctx, source, &memory{}, []byte("00000000000000000000000000000000")); err == nil {
+		t.Errorf("migrate() with wrong password succeeded")
+	}
+
+	// A destination that does not store the items unchanged is emptied again
+	broken := &lossy{&memory{}}
+	if _, err := migrate(ctx, source, broken, key); err == nil {
+		t.Errorf("migrate() into a lossy storage succeeded")
+	}
+	if len(broken.items) != 0 {
+		t.Errorf("migrate() left %d items in the destination after the verification failed", len(broken.items))
+	}
+}
+
+func TestMigrateVault(t *testing.T) {
+	ctx := context.Background()
+	key := []byte("12345678901234567890123456789012")
+
+	dir, err := ioutil.TempDir("", "passline-migrate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer config.SelectVault("")
+
+	file := filepath.Join(dir, "config.json")
+	data := `{"Directory": "` + dir + `", "Storage": "local", "Vaults": {"work": {}}}`
+	err = ioutil.WriteFile(file, []byte(data), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.UseFile(file)
+
+	config.SelectVault("work")
+	cfg, err := config.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	local, err := openStorage(ctx, "local")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := &Core{config: cfg, storage: local}
+	_, err = c.AddItem(ctx, "github.com", "perry", "secret", []string{}, Details{}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	count, err := c.Migrate(ctx, "", "sqlite", key)
+	if err != nil || count != 1 {
+		t.Fatalf("Migrate() = %d, %v; wanted 1 migrated item", count, err)
+	}
+
+	// Only the storage of the migrated vault is switched
+	cfg, _ = config.Read()
+	if cfg.Storage != "local" || cfg.Vaults["work"].Storage != "sqlite" {
+		t.Errorf("Migrate() of vault work configured %s for main and %s for work", cfg.Storage, cfg.Vaults["work"].Storage)
+	}
+}
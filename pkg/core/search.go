@@ -0,0 +1,81 @@
+package core
+
+import (
+	"context"
+	"sort"
+
+	"github.com/perryrh0dan/passline/pkg/storage"
+	"github.com/perryrh0dan/passline/pkg/util"
+)
+
+// Matches of other fields rank below equal matches of the item name
+const fieldPenalty = 8
+
+// SearchResult is an item matching a search with the field that matched best
+type SearchResult struct {
+	Item      storage.Item
+	Field     string
+	Value     string
+	Positions []int
+	score     int
+}
+
+// Search ranks the items by how well the query matches their name, urls,
+// folder, tags or usernames. Secrets are not decrypted.
+func (c *Core) Search(ctx context.Context, query string) ([]SearchResult, error) {
+	items, err := c.storage.GetAllItems(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return SearchItems(items, query), nil
+}
+
+// SearchItems ranks the given items like Search
+func SearchItems(items []storage.Item, query string) []SearchResult {
+	results := []SearchResult{}
+	for _, item := range items {
+		result, ok := matchItem(item, query)
+		if ok {
+			results = append(results, result)
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].score > results[j].score
+	})
+
+	return results
+}
+
+func matchItem(item storage.Item, query string) (SearchResult, bool) {
+	best := SearchResult{Item: item}
+	found := false
+
+	match := func(field, value string, penalty int) {
+		score, positions, ok := util.FuzzyMatch(query, value)
+		if !ok || found && score-penalty <= best.score {
+			return
+		}
+
+		found = true
+		best.Field = field
+		best.Value = value
+		best.Positions = positions
+		best.score = score - penalty
+	}
+
+	match("name", item.Name, 0)
+	for _, credential := range item.Credentials {
+		match("username", credential.Username, fieldPenalty)
+	}
+	for _, uri := range item.URIs {
+		match("url", uri.URI, fieldPenalty)
+	}
+	for _, tag := range item.Tags {
+		match("tag", tag, fieldPenalty)
+	}
+	match("folder", item.Folder, fieldPenalty)
+
+	return best, found
+}
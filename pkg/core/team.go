@@ -0,0 +1,282 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/perryrh0dan/passline/pkg/crypt"
+	"github.com/perryrh0dan/passline/pkg/storage"
+)
+
+// identity is the age X25519 key pair of the user, the private key is
+// encrypted with the global password
+type identity struct {
+	PublicKey  string `json:"publicKey"`
+	PrivateKey string `json:"privateKey"`
+}
+
+// CreateIdentity generates the key pair of the user and returns the public key
+func (c *Core) CreateIdentity(password []byte) (string, error) {
+	_, err := os.Stat(c.config.Identity)
+	if err == nil {
+		return "", errors.New("Identity " + c.config.Identity + " already exists")
+	}
+
+	publicKey, privateKey, err := crypt.GenerateIdentity()
+	if err != nil {
+		return "", err
+	}
+
+	encrypted, err := crypt.AesGcmEncrypt(password, privateKey)
+	if err != nil {
+		return "", err
+	}
+
+	id := identity{PublicKey: publicKey, PrivateKey: encrypted}
+	data, err := json.MarshalIndent(id, "", " ")
+	if err != nil {
+		return "", err
+	}
+
+	err = os.MkdirAll(filepath.Dir(c.config.Identity), 0700)
+	if err != nil {
+		return "", err
+	}
+
+	err = ioutil.WriteFile(c.config.Identity, data, 0600)
+	if err != nil {
+		return "", err
+	}
+
+	return id.PublicKey, nil
+}
+
+// PublicKey returns the public key of the user
+func (c *Core) PublicKey() (string, error) {
+	id, err := c.readIdentity()
+	if err != nil {
+		return "", err
+	}
+
+	return id.PublicKey, nil
+}
+
+// unlockTeam unwraps the vault key with the identity of the user
+func (c *Core) unlockTeam(ctx context.Context, password []byte) ([]byte, error) {
+	team, err := c.team()
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := c.readIdentity()
+	if err != nil {
+		return nil, err
+	}
+
+	privateKey, err := c.unlockIdentity(id, password)
+	if err != nil {
+		return nil, err
+	}
+
+	members, err := team.Members(ctx)
+	if err != nil {
+		return nil, errors.New("Team vault has no members, run passline team init")
+	}
+
+	for _, member := range members.Credentials {
+		if len(member.RecoveryCodes) == 1 && member.RecoveryCodes[0] == id.PublicKey {
+			return crypt.UnwrapKey(privateKey, member.Password)
+		}
+	}
+
+	return nil, errors.New("You are not a member of this team vault")
+}
+
+// InitTeam creates the vault key of an empty team vault with the user as
+// first member
+func (c *Core) InitTeam(ctx context.Context, name string, password []byte) error {
+	team, err := c.team()
+	if err != nil {
+		return err
+	}
+
+	if _, err := team.Members(ctx); err == nil {
+		return errors.New("Team vault is already initialized")
+	}
+
+	items, err := team.GetAllItems(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(items) > 0 {
+		return errors.New("Team vault is not empty")
+	}
+
+	id, err := c.readIdentity()
+	if err != nil {
+		return err
+	}
+
+	// Verify the password before the user is locked out
+	_, err = c.unlockIdentity(id, password)
+	if err != nil {
+		return err
+	}
+
+	vaultKey, err := crypt.GenerateVaultKey()
+	if err != nil {
+		return err
+	}
+
+	return c.addMember(ctx, team, name, id.PublicKey, vaultKey)
+}
+
+// AddMember wraps the vault key for the public key of a new member
+func (c *Core) AddMember(ctx context.Context, name, publicKey string, vaultKey []byte) error {
+	team, err := c.team()
+	if err != nil {
+		return err
+	}
+
+	members, err := c.Members(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, member := range members {
+		if member.Name == name || member.PublicKey == publicKey {
+			return errors.New("Member " + member.Name + " already exists")
+		}
+	}
+
+	return c.addMember(ctx, team, name, publicKey, vaultKey)
+}
+
+func (c *Core) addMember(ctx context.Context, team *storage.Keyed, name, publicKey string, vaultKey []byte) error {
+	wrapped, err := crypt.WrapKey(publicKey, vaultKey)
+	if err != nil {
+		return err
+	}
+
+	return team.AddMember(ctx, storage.Credential{Username: name, Password: wrapped, RecoveryCodes: []string{publicKey}})
+}
+
+// RemoveMember removes a member and replaces the vault key, so a copy of the
+// old key can not decrypt the items anymore
+func (c *Core) RemoveMember(ctx context.Context, name string, vaultKey []byte) error {
+	team, err := c.team()
+	if err != nil {
+		return err
+	}
+
+	members, err := c.Members(ctx)
+	if err != nil {
+		return err
+	}
+
+	remaining := []storage.Member{}
+	for _, member := range members {
+		if member.Name != name {
+			remaining = append(remaining, member)
+		}
+	}
+
+	if len(remaining) == len(members) {
+		return errors.New("Member " + name + " not found")
+	}
+
+	if len(remaining) == 0 {
+		return errors.New("The last member can not be removed")
+	}
+
+	newKey, err := crypt.GenerateVaultKey()
+	if err != nil {
+		return err
+	}
+
+	items, err := team.GetAllItems(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = c.reencrypt(items, vaultKey, newKey)
+	if err != nil {
+		return err
+	}
+
+	keys := storage.Item{Name: storage.MembersItem}
+	for _, member := range remaining {
+		wrapped, err := crypt.WrapKey(member.PublicKey, newKey)
+		if err != nil {
+			return err
+		}
+
+		keys.Credentials = append(keys.Credentials, storage.Credential{Username: member.Name, Password: wrapped, RecoveryCodes: []string{member.PublicKey}})
+	}
+
+	// Items and keys are replaced together, so they never use different keys
+	return team.SetData(ctx, storage.Data{Items: append(items, keys)})
+}
+
+// Members returns the members of the team vault
+func (c *Core) Members(ctx context.Context) ([]storage.Member, error) {
+	team, err := c.team()
+	if err != nil {
+		return nil, err
+	}
+
+	item, err := team.Members(ctx)
+	if err != nil {
+		return nil, errors.New("Team vault has no members, run passline team init")
+	}
+
+	members := []storage.Member{}
+	for _, credential := range item.Credentials {
+		member := storage.Member{Name: credential.Username}
+		if len(credential.RecoveryCodes) == 1 {
+			member.PublicKey = credential.RecoveryCodes[0]
+		}
+		members = append(members, member)
+	}
+
+	return members, nil
+}
+
+func (c *Core) team() (*storage.Keyed, error) {
+	team, ok := c.storage.(*storage.Keyed)
+	if !ok || c.config.Encryption != "team" {
+		return nil, errors.New("Vault " + c.config.Vault + " is not a team vault, set its Encryption to team")
+	}
+
+	return team, nil
+}
+
+func (c *Core) readIdentity() (identity, error) {
+	id := identity{}
+
+	data, err := ioutil.ReadFile(c.config.Identity)
+	if os.IsNotExist(err) {
+		return id, errors.New("No identity found, create one with passline team identity")
+	}
+	if err != nil {
+		return id, err
+	}
+
+	err = json.Unmarshal(data, &id)
+	return id, err
+}
+
+// unlockIdentity returns the age identity of the user
+func (c *Core) unlockIdentity(id identity, password []byte) (string, error) {
+	privateKey, err := crypt.AesGcmDecrypt(password, id.PrivateKey)
+	if err != nil {
+		return "", errors.New("Invalid password")
+	}
+
+	return privateKey, nil
+}
@@ -0,0 +1,159 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/perryrh0dan/passline/pkg/config"
+	"github.com/perryrh0dan/passline/pkg/crypt"
+)
+
+// newTestTeam returns an initialized team vault with alice as first member
+// and the public key of bob, who is not a member yet
+func newTestTeam(t *testing.T) (*Core, string, func()) {
+	c, _, cleanup := newTestCore(t, config.Config{Encryption: "team"})
+
+	c.config.Identity = filepath.Join(c.config.Directory, "bob.json")
+	bob, err := c.CreateIdentity([]byte("bob"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.config.Identity = filepath.Join(c.config.Directory, "alice.json")
+	_, err = c.CreateIdentity([]byte("alice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = c.InitTeam(context.Background(), "alice", []byte("alice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return c, bob, cleanup
+}
+
+// unlockAs returns the vault key unwrapped with the identity of name
+func unlockAs(c *Core, name string) ([]byte, error) {
+	identity := c.config.Identity
+	defer func() { c.config.Identity = identity }()
+
+	c.config.Identity = filepath.Join(c.config.Directory, name+".json")
+	return c.UnlockKey(context.Background(), []byte(name))
+}
+
+func TestTeam(t *testing.T) {
+	ctx := context.Background()
+	c, bob, cleanup := newTestTeam(t)
+	defer cleanup()
+
+	if err := c.InitTeam(ctx, "alice", []byte("alice")); err == nil {
+		t.Errorf("InitTeam() of an initialized vault succeeded")
+	}
+
+	key, err := unlockAs(c, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := unlockAs(c, "bob"); err == nil {
+		t.Errorf("UnlockKey() of a user that is not a member succeeded")
+	}
+
+	_, err = c.AddItem(ctx, "github.com", "perry", "secret", []string{}, Details{}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = c.AddMember(ctx, "bob", bob, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.AddMember(ctx, "bob", bob, key); err == nil {
+		t.Errorf("AddMember() of an existing member succeeded")
+	}
+
+	bobKey, err := unlockAs(c, "bob")
+	if err != nil || !bytes.Equal(bobKey, key) {
+		t.Errorf("UnlockKey() as new member = %v; wanted the vault key", err)
+	}
+
+	// Removing a member replaces the vault key
+	err = c.RemoveMember(ctx, "bob", key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := unlockAs(c, "bob"); err == nil {
+		t.Errorf("UnlockKey() of a removed member succeeded")
+	}
+
+	newKey, err := unlockAs(c, "alice")
+	if err != nil || bytes.Equal(newKey, key) {
+		t.Fatalf("UnlockKey() after the removal = %v; wanted a new vault key", err)
+	}
+
+	item, _ := c.GetSite(ctx, "github.com")
+	credential := item.Credentials[0]
+	if _, err := crypt.AesGcmDecrypt(key, credential.Password); err == nil {
+		t.Errorf("The old vault key decrypts the items after the removal")
+	}
+	if err := c.DecryptPassword(&credential, newKey); err != nil || credential.Password != "secret" {
+		t.Errorf("DecryptPassword() with the new vault key = %v", err)
+	}
+
+	if err := c.RemoveMember(ctx, "alice", newKey); err == nil {
+		t.Errorf("RemoveMember() of the last member succeeded")
+	}
+}
+
+func TestTeamBackup(t *testing.T) {
+	ctx := context.Background()
+	c, bob, cleanup := newTestTeam(t)
+	defer cleanup()
+
+	key, _ := unlockAs(c, "alice")
+	_, err := c.AddItem(ctx, "github.com", "perry", "secret", []string{}, Details{}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = c.AddMember(ctx, "bob", bob, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(c.config.Directory, "backup.json")
+	err = c.CreateBackup(ctx, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The backup keeps the vault key it was encrypted with after a re-key
+	err = c.RemoveMember(ctx, "bob", key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = c.RestoreBackup(ctx, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"alice", "bob"} {
+		restored, err := unlockAs(c, name)
+		if err != nil || !bytes.Equal(restored, key) {
+			t.Errorf("UnlockKey() as %s after the restore = %v; wanted the key of the backup", name, err)
+			continue
+		}
+
+		item, _ := c.GetSite(ctx, "github.com")
+		credential := item.Credentials[0]
+		if err := c.DecryptPassword(&credential, restored); err != nil || credential.Password != "secret" {
+			t.Errorf("DecryptPassword() as %s after the restore = %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package crypt
+
+import (
+	"bytes"
+	"io/ioutil"
+
+	"filippo.io/age"
+)
+
+// AgeEncrypt encrypts plaintext to the age recipients
+func AgeEncrypt(plaintext []byte, recipients ...age.Recipient) ([]byte, error) {
+	buffer := &bytes.Buffer{}
+	w, err := age.Encrypt(buffer, recipients...)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := w.Write(plaintext); err != nil {
+		return nil, err
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// AgeDecrypt decrypts an age file with the first matching identity
+func AgeDecrypt(ciphertext []byte, identities ...age.Identity) ([]byte, error) {
+	r, err := age.Decrypt(bytes.NewReader(ciphertext), identities...)
+	if err != nil {
+		return nil, err
+	}
+
+	return ioutil.ReadAll(r)
+}
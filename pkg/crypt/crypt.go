@@ -5,6 +5,7 @@ import (
 	"crypto/cipher"
 	"crypto/rand"
 	"crypto/sha1"
+	"crypto/sha256"
 	"encoding/base64"
 	"fmt"
 	"io"
@@ -111,3 +112,22 @@ func GeneratePassword(length int) (string, error) {
 	password := string(a)
 	return password, nil
 }
+
+// CompositeKey combines the password with the content of a key file, both
+// are required to derive the key
+func CompositeKey(password, keyFile []byte) []byte {
+	passwordHash := sha256.Sum256(password)
+	keyFileHash := sha256.Sum256(keyFile)
+	composite := sha256.Sum256(append(passwordHash[:], keyFileHash[:]...))
+	return composite[:]
+}
+
+// GenerateKeyFile returns random content for a new key file
+func GenerateKeyFile() ([]byte, error) {
+	data := make([]byte, 64)
+	if _, err := io.ReadFull(rand.Reader, data); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
@@ -21,3 +21,62 @@ func TestDecrypt(t *testing.T) {
 		t.Errorf("Encrypt(1234, %s) = %s; wanted %s", cryptedText, got, encryptedText)
 	}
 }
+
+func TestWrapKey(t *testing.T) {
+	publicKey, privateKey, err := GenerateIdentity()
+	if err != nil {
+		t.Fatalf("GenerateIdentity() error: %v", err)
+	}
+
+	key, _ := GenerateVaultKey()
+	wrapped, err := WrapKey(publicKey, key)
+	if err != nil {
+		t.Fatalf("WrapKey() error: %v", err)
+	}
+
+	got, err := UnwrapKey(privateKey, wrapped)
+	if err != nil || string(got) != string(key) {
+		t.Errorf("UnwrapKey() = %x, %v; wanted %x", got, err, key)
+	}
+
+	_, otherKey, _ := GenerateIdentity()
+	if _, err := UnwrapKey(otherKey, wrapped); err == nil {
+		t.Errorf("UnwrapKey() with another identity succeeded")
+	}
+}
+
+func TestCompositeKey(t *testing.T) {
+	keyFile, _ := GenerateKeyFile()
+	otherKeyFile, _ := GenerateKeyFile()
+
+	encrypted, _ := AesGcmEncrypt(CompositeKey([]byte("1234"), keyFile), encryptedText)
+
+	if _, err := AesGcmDecrypt([]byte("1234"), encrypted); err == nil {
+		t.Errorf("AesGcmDecrypt() without key file succeeded")
+	}
+
+	if _, err := AesGcmDecrypt(CompositeKey([]byte("1234"), otherKeyFile), encrypted); err == nil {
+		t.Errorf("AesGcmDecrypt() with another key file succeeded")
+	}
+
+	got, err := AesGcmDecrypt(CompositeKey([]byte("1234"), keyFile), encrypted)
+	if err != nil || got != encryptedText {
+		t.Errorf("AesGcmDecrypt() with key file = %s, %v; wanted %s", got, err, encryptedText)
+	}
+}
+
+func TestPasswordStrength(t *testing.T) {
+	weak := []string{"password", "P@ssw0rd", "qwerty123", "aaaaaaaa", "abcdef", "monkey1990", "123456789"}
+	for _, password := range weak {
+		if bits, score := PasswordStrength(password); score >= 3 {
+			t.Errorf("PasswordStrength(%q) = %.1f bits, score %d; wanted a weak score", password, bits, score)
+		}
+	}
+
+	strong := []string{"correct-Horse7-battery", "tK9#vQ2$mWx!", "u4WXM56bZatFTS4-quqhlY"}
+	for _, password := range strong {
+		if bits, score := PasswordStrength(password); score < 4 {
+			t.Errorf("PasswordStrength(%q) = %.1f bits, score %d; wanted score 4", password, bits, score)
+		}
+	}
+}
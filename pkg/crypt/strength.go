@@ -0,0 +1,206 @@
+package crypt
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// commonPasswords are ranked by how often they are used, lower ranks are
+// guessed first
+var commonPasswords = strings.Fields(`
+	password 123456 12345678 qwerty abc123 monkey letmein dragon 111111
+	baseball iloveyou trustno1 sunshine master welcome shadow ashley football
+	jesus michael ninja mustang password1 admin login princess starwars
+	solo qazwsx hello freedom whatever charlie aa123456 donald batman zaq1zaq1
+	superman secret access flower hottie loveme passw lovely summer winter
+	spring autumn google apple orange banana cookie computer internet
+	pokemon soccer hockey killer pepper jordan hunter ranger buster thomas
+	robert tigger daniel hannah maggie jessica matthew andrew joshua
+	chocolate cheese purple yellow silver golden diamond love happy family
+	forever friends secure change default guest root test user changeme
+	passline company office money account server
+`)
+
+// keyboardRows are scanned for runs of adjacent keys
+var keyboardRows = []string{
+	"`1234567890-=", "qwertyuiop[]", "asdfghjkl;'", "zxcvbnm,./",
+	"qwertzuiop", "yxcvbnm", "azertyuiop", "qsdfghjklm", "wxcvbn",
+}
+
+var leet = map[rune]rune{'4': 'a', '@': 'a', '8': 'b', '3': 'e', '6': 'g', '1': 'i', '!': 'i', '0': 'o', '$': 's', '5': 's', '7': 't', '+': 't', '2': 'z'}
+
+// PasswordStrength estimates the entropy of a password in bits like zxcvbn.
+// The password is split into dictionary words, repeats, sequences, keyboard
+// runs and years, everything else is counted as random characters. The score
+// goes from 0 (guessed within 10^3 tries) to 4 (more than 10^10 tries).
+func PasswordStrength(password string) (float64, int) {
+	runes := []rune(password)
+	if len(runes) == 0 {
+		return 0, 0
+	}
+
+	charBits := math.Log2(float64(cardinality(runes)))
+
+	// best[i] is the lowest entropy of the first i characters
+	best := make([]float64, len(runes)+1)
+	for i := 1; i <= len(runes); i++ {
+		best[i] = math.Inf(1)
+	}
+
+	for i := 0; i < len(runes); i++ {
+		if best[i]+charBits < best[i+1] {
+			best[i+1] = best[i] + charBits
+		}
+
+		for _, m := range patterns(runes, i) {
+			if best[i]+m.bits < best[i+m.length] {
+				best[i+m.length] = best[i] + m.bits
+			}
+		}
+	}
+
+	bits := best[len(runes)]
+
+	// Thresholds of 10^3, 10^6, 10^8 and 10^10 guesses
+	score := 0
+	for _, threshold := range []float64{10, 20, 26.6, 33.2} {
+		if bits >= threshold {
+			score++
+		}
+	}
+
+	return bits, score
+}
+
+// match is a pattern found in the password
+type match struct {
+	length int
+	bits   float64
+}
+
+// patterns returns the patterns starting at index i
+func patterns(runes []rune, i int) []match {
+	matches := []match{}
+
+	lower := make([]rune, len(runes))
+	unleet := make([]rune, len(runes))
+	for k, r := range runes {
+		lower[k] = unicode.ToLower(r)
+		unleet[k] = lower[k]
+		if l, ok := leet[r]; ok {
+			unleet[k] = l
+		}
+	}
+
+	// Dictionary words, capitalization and leet substitutions add a bit each
+	for rank, word := range commonPasswords {
+		w := []rune(word)
+		if i+len(w) > len(runes) {
+			continue
+		}
+
+		bits := math.Log2(float64(rank + 2))
+		switch {
+		case string(lower[i:i+len(w)]) == word:
+		case string(unleet[i:i+len(w)]) == word:
+			bits++
+		default:
+			continue
+		}
+
+		if string(runes[i:i+len(w)]) != string(lower[i:i+len(w)]) {
+			bits++
+		}
+		matches = append(matches, match{length: len(w), bits: bits})
+	}
+
+	// Repeated characters
+	j := i + 1
+	for j < len(runes) && runes[j] == runes[i] {
+		j++
+	}
+	if j-i >= 3 {
+		matches = append(matches, match{length: j - i, bits: math.Log2(float64(cardinality(runes[i:i+1]))) + math.Log2(float64(j-i))})
+	}
+
+	// Sequences like abcd or 9876
+	if i+2 < len(runes) {
+		delta := lower[i+1] - lower[i]
+		if delta == 1 || delta == -1 {
+			j := i + 1
+			for j < len(runes) && lower[j]-lower[j-1] == delta {
+				j++
+			}
+			if j-i >= 3 {
+				bits := math.Log2(float64(cardinality(runes[i:i+1]))) + math.Log2(float64(j-i))
+				if delta < 0 {
+					bits++
+				}
+				matches = append(matches, match{length: j - i, bits: bits})
+			}
+		}
+	}
+
+	// Runs of adjacent keys
+	for _, row := range keyboardRows {
+		for length := len(row); length >= 4; length-- {
+			if i+length > len(lower) {
+				continue
+			}
+
+			if strings.Contains(row, string(lower[i:i+length])) {
+				matches = append(matches, match{length: length, bits: math.Log2(float64(len(row))) + math.Log2(float64(length))})
+				break
+			}
+		}
+	}
+
+	// Years from 1900 to 2039
+	if i+4 <= len(runes) {
+		year := string(runes[i : i+4])
+		if (strings.HasPrefix(year, "19") || strings.HasPrefix(year, "20")) && year[2] >= '0' && year[2] <= '9' && year[3] >= '0' && year[3] <= '9' && year < "2040" {
+			matches = append(matches, match{length: 4, bits: math.Log2(140)})
+		}
+	}
+
+	return matches
+}
+
+// cardinality is the size of the character classes used in runes
+func cardinality(runes []rune) int {
+	var lower, upper, digit, symbol, other bool
+	for _, r := range runes {
+		switch {
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= '0' && r <= '9':
+			digit = true
+		case r < unicode.MaxASCII:
+			symbol = true
+		default:
+			other = true
+		}
+	}
+
+	size := 0
+	if lower {
+		size += 26
+	}
+	if upper {
+		size += 26
+	}
+	if digit {
+		size += 10
+	}
+	if symbol {
+		size += 33
+	}
+	if other {
+		size += 100
+	}
+
+	return size
+}
@@ -0,0 +1,62 @@
+package crypt
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io"
+
+	"filippo.io/age"
+)
+
+// GenerateIdentity returns a new age X25519 identity and its recipient
+func GenerateIdentity() (recipient, identity string, err error) {
+	id, err := age.GenerateX25519Identity()
+	if err != nil {
+		return "", "", err
+	}
+
+	return id.Recipient().String(), id.String(), nil
+}
+
+// GenerateVaultKey returns a random key that is used in place of the global
+// password of a vault
+func GenerateVaultKey() ([]byte, error) {
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// WrapKey encrypts a key to an age X25519 recipient (age1...), the result
+// is a base64 encoded age file
+func WrapKey(recipient string, key []byte) (string, error) {
+	r, err := age.ParseX25519Recipient(recipient)
+	if err != nil {
+		return "", errors.New("Invalid public key")
+	}
+
+	wrapped, err := AgeEncrypt(key, r)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(wrapped), nil
+}
+
+// UnwrapKey decrypts a key that was wrapped for the recipient of identity
+func UnwrapKey(identity string, wrapped string) ([]byte, error) {
+	id, err := age.ParseX25519Identity(identity)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := base64.StdEncoding.DecodeString(wrapped)
+	if err != nil {
+		return nil, err
+	}
+
+	return AgeDecrypt(data, id)
+}
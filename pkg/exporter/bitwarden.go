@@ -0,0 +1,134 @@
+package exporter
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/perryrh0dan/passline/pkg/storage"
+)
+
+// Match types of bitwarden uris
+const (
+	bitwardenMatchHost  = 1
+	bitwardenMatchExact = 3
+	bitwardenMatchRegex = 4
+)
+
+type bitwardenExport struct {
+	Encrypted bool              `json:"encrypted"`
+	Folders   []bitwardenFolder `json:"folders"`
+	Items     []bitwardenItem   `json:"items"`
+}
+
+type bitwardenFolder struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type bitwardenItem struct {
+	ID             string         `json:"id"`
+	OrganizationID *string        `json:"organizationId"`
+	FolderID       *string        `json:"folderId"`
+	Type           int            `json:"type"`
+	Name           string         `json:"name"`
+	Notes          *string        `json:"notes"`
+	Favorite       bool           `json:"favorite"`
+	Login          bitwardenLogin `json:"login"`
+	CollectionIds  []string       `json:"collectionIds"`
+}
+
+type bitwardenLogin struct {
+	Uris     []bitwardenURI `json:"uris"`
+	Username string         `json:"username"`
+	Password string         `json:"password"`
+	Totp     *string        `json:"totp"`
+}
+
+type bitwardenURI struct {
+	Match *int   `json:"match"`
+	URI   string `json:"uri"`
+}
+
+// writeBitwarden writes an unencrypted bitwarden json export
+func writeBitwarden(w io.Writer, items []storage.Item) error {
+	data := bitwardenExport{Folders: []bitwardenFolder{}, Items: []bitwardenItem{}}
+	folders := map[string]string{}
+
+	for _, item := range items {
+		var folderID *string
+		if item.Folder != "" {
+			id, ok := folders[item.Folder]
+			if !ok {
+				uuid, err := newUUID()
+				if err != nil {
+					return err
+				}
+
+				id = formatUUID(uuid)
+				folders[item.Folder] = id
+				data.Folders = append(data.Folders, bitwardenFolder{ID: id, Name: item.Folder})
+			}
+			folderID = &id
+		}
+
+		for _, credential := range item.Credentials {
+			uuid, err := newUUID()
+			if err != nil {
+				return err
+			}
+
+			bwItem := bitwardenItem{
+				ID:       formatUUID(uuid),
+				FolderID: folderID,
+				Type:     1,
+				Name:     item.Name,
+				Login: bitwardenLogin{
+					Uris:     bitwardenURIs(item),
+					Username: credential.Username,
+					Password: credential.Password,
+				},
+			}
+
+			if n := notes(credential); n != "" {
+				bwItem.Notes = &n
+			}
+
+			data.Items = append(data.Items, bwItem)
+		}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(data)
+}
+
+// bitwardenURIs returns the uris of the item with their match types, items
+// without uris are matched by their name
+func bitwardenURIs(item storage.Item) []bitwardenURI {
+	uris := []bitwardenURI{}
+	for _, uri := range item.URIs {
+		var match *int
+		switch uri.Match {
+		case storage.MatchHost:
+			match = intPtr(bitwardenMatchHost)
+		case storage.MatchExact:
+			match = intPtr(bitwardenMatchExact)
+		case storage.MatchRegex:
+			match = intPtr(bitwardenMatchRegex)
+		}
+
+		uris = append(uris, bitwardenURI{Match: match, URI: uri.URI})
+	}
+
+	if len(uris) == 0 {
+		if url := itemURL(item.Name); url != "" {
+			uris = append(uris, bitwardenURI{URI: url})
+		}
+	}
+
+	return uris
+}
+
+func intPtr(i int) *int {
+	return &i
+}
@@ -0,0 +1,38 @@
+package exporter
+
+import (
+	"encoding/csv"
+	"io"
+
+	"github.com/perryrh0dan/passline/pkg/storage"
+	"github.com/perryrh0dan/passline/pkg/util"
+)
+
+// writeKeePassCSV writes the csv layout of KeePassXC
+func writeKeePassCSV(w io.Writer, items []storage.Item) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"Group", "Title", "Username", "Password", "URL", "Notes"})
+
+	for _, item := range items {
+		for _, credential := range item.Credentials {
+			writer.Write([]string{"Passline", item.Name, credential.Username, credential.Password, itemURL(item.Name), notes(credential)})
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func writeCSV(w io.Writer, items []storage.Item) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"name", "username", "password", "recovery_codes"})
+
+	for _, item := range items {
+		for _, credential := range item.Credentials {
+			writer.Write([]string{item.Name, credential.Username, credential.Password, util.ArrayToString(credential.RecoveryCodes)})
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
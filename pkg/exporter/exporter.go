@@ -0,0 +1,82 @@
+package exporter
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/perryrh0dan/passline/pkg/storage"
+	"github.com/perryrh0dan/passline/pkg/util"
+)
+
+// Writer writes decrypted items in the format of another password manager
+type Writer func(w io.Writer, items []storage.Item) error
+
+var writers = map[string]Writer{
+	"bitwarden":   writeBitwarden,
+	"keepass-csv": writeKeePassCSV,
+	"keepass-xml": writeKeePassXML,
+	"csv":         writeCSV,
+}
+
+// Formats returns the names of all supported export formats
+func Formats() []string {
+	formats := make([]string, 0, len(writers))
+	for format := range writers {
+		formats = append(formats, format)
+	}
+
+	sort.Strings(formats)
+	return formats
+}
+
+// Write writes the decrypted items to w in the given format
+func Write(format string, w io.Writer, items []storage.Item) error {
+	writer, ok := writers[format]
+	if !ok {
+		return errors.New("Unknown export format: " + format)
+	}
+
+	return writer(w, items)
+}
+
+// itemURL turns an item name into an url if it looks like a domain
+func itemURL(name string) string {
+	if strings.Contains(name, "://") {
+		return name
+	}
+
+	if strings.Contains(name, ".") && !strings.Contains(name, " ") {
+		return "https://" + name
+	}
+
+	return ""
+}
+
+func notes(credential storage.Credential) string {
+	if len(credential.RecoveryCodes) == 0 {
+		return ""
+	}
+
+	return "Recovery codes: " + util.ArrayToString(credential.RecoveryCodes)
+}
+
+func newUUID() ([]byte, error) {
+	uuid := make([]byte, 16)
+	_, err := io.ReadFull(rand.Reader, uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	// Version 4, variant 10
+	uuid[6] = (uuid[6] & 0x0f) | 0x40
+	uuid[8] = (uuid[8] & 0x3f) | 0x80
+	return uuid, nil
+}
+
+func formatUUID(uuid []byte) string {
+	return fmt.Sprintf("%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:])
+}
@@ -0,0 +1,46 @@
+package exporter
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/perryrh0dan/passline/pkg/storage"
+)
+
+var items = []storage.Item{
+	{Name: "github.com", Credentials: []storage.Credential{
+		{Username: "perry", Password: "secret", RecoveryCodes: []string{"a", "b"}},
+	}},
+}
+
+func TestWriteBitwarden(t *testing.T) {
+	var buf bytes.Buffer
+	err := Write("bitwarden", &buf, items)
+	if err != nil {
+		t.Fatalf("Write(bitwarden) error: %v", err)
+	}
+
+	data := bitwardenExport{}
+	err = json.Unmarshal(buf.Bytes(), &data)
+	if err != nil {
+		t.Fatalf("Write(bitwarden) produced invalid json: %v", err)
+	}
+
+	if len(data.Items) != 1 || data.Items[0].Login.Password != "secret" || data.Items[0].Login.Uris[0].URI != "https://github.com" {
+		t.Errorf("Write(bitwarden) = %s", buf.String())
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	err := Write("csv", &buf, items)
+	if err != nil {
+		t.Fatalf("Write(csv) error: %v", err)
+	}
+
+	want := "name,username,password,recovery_codes\ngithub.com,perry,secret,\"a,b\"\n"
+	if got := buf.String(); got != want {
+		t.Errorf("Write(csv) = %q; wanted %q", got, want)
+	}
+}
@@ -0,0 +1,81 @@
+package exporter
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"io"
+
+	"github.com/perryrh0dan/passline/pkg/storage"
+)
+
+type keePassFile struct {
+	XMLName xml.Name `xml:"KeePassFile"`
+	Meta    struct {
+		Generator string `xml:"Generator"`
+	} `xml:"Meta"`
+	Root struct {
+		Group keePassGroup `xml:"Group"`
+	} `xml:"Root"`
+}
+
+type keePassGroup struct {
+	UUID    string         `xml:"UUID"`
+	Name    string         `xml:"Name"`
+	Entries []keePassEntry `xml:"Entry"`
+}
+
+type keePassEntry struct {
+	UUID    string          `xml:"UUID"`
+	Strings []keePassString `xml:"String"`
+}
+
+type keePassString struct {
+	Key   string       `xml:"Key"`
+	Value keePassValue `xml:"Value"`
+}
+
+type keePassValue struct {
+	Value           string `xml:",chardata"`
+	ProtectInMemory string `xml:"ProtectInMemory,attr,omitempty"`
+}
+
+// writeKeePassXML writes a KeePass 2 xml export with one group for all items
+func writeKeePassXML(w io.Writer, items []storage.Item) error {
+	data := keePassFile{}
+	data.Meta.Generator = "Passline"
+
+	uuid, err := newUUID()
+	if err != nil {
+		return err
+	}
+	data.Root.Group = keePassGroup{UUID: base64.StdEncoding.EncodeToString(uuid), Name: "Passline"}
+
+	for _, item := range items {
+		for _, credential := range item.Credentials {
+			uuid, err := newUUID()
+			if err != nil {
+				return err
+			}
+
+			data.Root.Group.Entries = append(data.Root.Group.Entries, keePassEntry{
+				UUID: base64.StdEncoding.EncodeToString(uuid),
+				Strings: []keePassString{
+					{Key: "Title", Value: keePassValue{Value: item.Name}},
+					{Key: "UserName", Value: keePassValue{Value: credential.Username}},
+					{Key: "Password", Value: keePassValue{Value: credential.Password, ProtectInMemory: "True"}},
+					{Key: "URL", Value: keePassValue{Value: itemURL(item.Name)}},
+					{Key: "Notes", Value: keePassValue{Value: notes(credential)}},
+				},
+			})
+		}
+	}
+
+	_, err = io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "\t")
+	return encoder.Encode(data)
+}
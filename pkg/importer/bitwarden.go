@@ -0,0 +1,130 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"regexp"
+
+	"github.com/perryrh0dan/passline/pkg/storage"
+)
+
+const bitwardenTypeLogin = 1
+
+// Match types of bitwarden uris, null is the default domain match
+const (
+	bitwardenMatchDomain     = 0
+	bitwardenMatchHost       = 1
+	bitwardenMatchStartsWith = 2
+	bitwardenMatchExact      = 3
+	bitwardenMatchRegex      = 4
+	bitwardenMatchNever      = 5
+)
+
+type bitwardenExport struct {
+	Encrypted bool              `json:"encrypted"`
+	Folders   []bitwardenFolder `json:"folders"`
+	Items     []bitwardenItem   `json:"items"`
+}
+
+type bitwardenFolder struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type bitwardenItem struct {
+	Type     int     `json:"type"`
+	Name     string  `json:"name"`
+	FolderID *string `json:"folderId"`
+	Login    struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+		Uris     []struct {
+			Match *int   `json:"match"`
+			URI   string `json:"uri"`
+		} `json:"uris"`
+	} `json:"login"`
+}
+
+func parseBitwarden(path string) ([]storage.Item, error) {
+	file, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	data := bitwardenExport{}
+	err = json.Unmarshal(file, &data)
+	if err != nil {
+		return nil, err
+	}
+
+	if data.Encrypted {
+		return nil, errors.New("Encrypted bitwarden exports are not supported")
+	}
+
+	folders := map[string]string{}
+	for _, folder := range data.Folders {
+		folders[folder.ID] = folder.Name
+	}
+
+	entries := []entry{}
+	for _, item := range data.Items {
+		if item.Type != bitwardenTypeLogin {
+			continue
+		}
+
+		url := ""
+		uris := []storage.URI{}
+		for _, u := range item.Login.Uris {
+			if url == "" {
+				url = u.URI
+			}
+
+			if uri, ok := bitwardenURI(u.URI, u.Match); ok {
+				uris = append(uris, uri)
+			}
+		}
+
+		folder := ""
+		if item.FolderID != nil {
+			folder = folders[*item.FolderID]
+		}
+
+		entries = append(entries, entry{
+			title:    item.Name,
+			url:      url,
+			username: item.Login.Username,
+			password: item.Login.Password,
+			folder:   folder,
+			uris:     uris,
+		})
+	}
+
+	return group(entries), nil
+}
+
+// bitwardenURI converts an uri with its bitwarden match type, invalid uris
+// and those that are never matched are skipped
+func bitwardenURI(value string, match *int) (storage.URI, bool) {
+	uri := storage.URI{URI: value}
+	if match == nil {
+		match = new(int)
+	}
+
+	switch *match {
+	case bitwardenMatchHost:
+		uri.Match = storage.MatchHost
+	case bitwardenMatchStartsWith:
+		uri.URI = "^" + regexp.QuoteMeta(value)
+		uri.Match = storage.MatchRegex
+	case bitwardenMatchExact:
+		uri.Match = storage.MatchExact
+	case bitwardenMatchRegex:
+		uri.Match = storage.MatchRegex
+	case bitwardenMatchNever:
+		return uri, false
+	}
+
+	uri, err := storage.ParseURI(uri.String())
+	return uri, err == nil
+}
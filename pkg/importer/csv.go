@@ -0,0 +1,150 @@
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/perryrh0dan/passline/pkg/storage"
+)
+
+// columns lists the accepted header names for each field, lower case
+type columns struct {
+	title    []string
+	url      []string
+	username []string
+	password []string
+	// skip reports entries that are no credentials
+	skip func(e entry) bool
+}
+
+func parseKeePassCSV(path string) ([]storage.Item, error) {
+	return parseCSV(path, columns{
+		title:    []string{"title", "account"},
+		url:      []string{"url", "web site"},
+		username: []string{"username", "login name", "user name"},
+		password: []string{"password"},
+	})
+}
+
+func parseOnePassword(path string) ([]storage.Item, error) {
+	return parseCSV(path, columns{
+		title:    []string{"title"},
+		url:      []string{"url", "website", "login_url"},
+		username: []string{"username", "login_username"},
+		password: []string{"password", "login_password"},
+	})
+}
+
+func parseLastPass(path string) ([]storage.Item, error) {
+	return parseCSV(path, columns{
+		title:    []string{"name"},
+		url:      []string{"url"},
+		username: []string{"username"},
+		password: []string{"password"},
+		// Secure notes are exported with the url http://sn
+		skip: func(e entry) bool { return e.url == "http://sn" },
+	})
+}
+
+func parseChrome(path string) ([]storage.Item, error) {
+	return parseCSV(path, columns{
+		title:    []string{"name"},
+		url:      []string{"url", "origin_url"},
+		username: []string{"username", "username_value"},
+		password: []string{"password", "password_value"},
+	})
+}
+
+func parseFirefox(path string) ([]storage.Item, error) {
+	return parseCSV(path, columns{
+		title:    []string{"hostname"},
+		url:      []string{"url", "hostname"},
+		username: []string{"username"},
+		password: []string{"password"},
+	})
+}
+
+func parseCSV(path string, cols columns) ([]storage.Item, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	entries, err := readCSV(file, cols)
+	if err != nil {
+		return nil, err
+	}
+
+	return group(entries), nil
+}
+
+func readCSV(r io.Reader, cols columns) ([]entry, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	title := findColumn(header, cols.title)
+	url := findColumn(header, cols.url)
+	username := findColumn(header, cols.username)
+	password := findColumn(header, cols.password)
+
+	if password == -1 || (title == -1 && url == -1) {
+		return nil, errors.New("Unsupported csv header: " + strings.Join(header, ","))
+	}
+
+	entries := []entry{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		e := entry{
+			title:    field(record, title),
+			url:      field(record, url),
+			username: field(record, username),
+			password: field(record, password),
+		}
+
+		if cols.skip != nil && cols.skip(e) {
+			continue
+		}
+		entries = append(entries, e)
+	}
+
+	return entries, nil
+}
+
+func findColumn(header []string, names []string) int {
+	for _, name := range names {
+		for i, h := range header {
+			// Strip a possible byte order mark
+			h = strings.TrimPrefix(h, "\ufeff")
+			if strings.EqualFold(strings.TrimSpace(h), name) {
+				return i
+			}
+		}
+	}
+
+	return -1
+}
+
+func field(record []string, index int) string {
+	if index < 0 || index >= len(record) {
+		return ""
+	}
+
+	return record[index]
+}
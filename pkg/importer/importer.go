@@ -0,0 +1,130 @@
+package importer
+
+import (
+	"errors"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/perryrh0dan/passline/pkg/storage"
+)
+
+// Parser reads an export of another password manager and returns the
+// contained credentials in plaintext
+type Parser func(path string) ([]storage.Item, error)
+
+var parsers = map[string]Parser{
+	"keepass-xml": parseKeePassXML,
+	"keepass-csv": parseKeePassCSV,
+	"bitwarden":   parseBitwarden,
+	"1password":   parseOnePassword,
+	"lastpass":    parseLastPass,
+	"chrome":      parseChrome,
+	"firefox":     parseFirefox,
+	"pass":        parsePass,
+}
+
+// Formats returns the names of all supported import formats
+func Formats() []string {
+	formats := make([]string, 0, len(parsers))
+	for format := range parsers {
+		formats = append(formats, format)
+	}
+
+	sort.Strings(formats)
+	return formats
+}
+
+// Parse reads the file or directory at path with the parser for format
+func Parse(format, path string) ([]storage.Item, error) {
+	parser, ok := parsers[format]
+	if !ok {
+		return nil, errors.New("Unknown import format: " + format)
+	}
+
+	return parser(path)
+}
+
+// entry is a single credential as found in an export
+type entry struct {
+	title    string
+	url      string
+	username string
+	password string
+	// Optional details of the item, not every export has them
+	folder string
+	uris   []storage.URI
+}
+
+// group merges entries into items. The item name is the host of the url if
+// available, otherwise the title. Entries without password are skipped.
+func group(entries []entry) []storage.Item {
+	items := []storage.Item{}
+	index := map[string]int{}
+
+	for _, e := range entries {
+		if e.password == "" {
+			continue
+		}
+
+		name := itemName(e.title, e.url)
+		if name == "" {
+			continue
+		}
+
+		credential := storage.Credential{Username: e.username, Password: e.password, RecoveryCodes: []string{}}
+
+		i, ok := index[name]
+		if !ok {
+			index[name] = len(items)
+			items = append(items, storage.Item{Name: name, Folder: e.folder, URIs: e.uris, Credentials: []storage.Credential{credential}})
+			continue
+		}
+
+		if items[i].Folder == "" {
+			items[i].Folder = e.folder
+		}
+		items[i].URIs = mergeURIs(items[i].URIs, e.uris)
+
+		if _, err := items[i].GetCredentialByUsername(e.username); err == nil {
+			continue
+		}
+		items[i].Credentials = append(items[i].Credentials, credential)
+	}
+
+	return items
+}
+
+// mergeURIs appends the uris that are not in existing yet
+func mergeURIs(existing, uris []storage.URI) []storage.URI {
+	for _, uri := range uris {
+		found := false
+		for _, e := range existing {
+			if e == uri {
+				found = true
+			}
+		}
+
+		if !found {
+			existing = append(existing, uri)
+		}
+	}
+
+	return existing
+}
+
+func itemName(title, rawURL string) string {
+	rawURL = strings.TrimSpace(rawURL)
+	if rawURL != "" {
+		if !strings.Contains(rawURL, "://") {
+			rawURL = "https://" + rawURL
+		}
+
+		u, err := url.Parse(rawURL)
+		if err == nil && u.Hostname() != "" && strings.Contains(u.Hostname(), ".") {
+			return strings.TrimPrefix(u.Hostname(), "www.")
+		}
+	}
+
+	return strings.TrimSpace(title)
+}
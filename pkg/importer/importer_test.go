@@ -0,0 +1,95 @@
+package importer
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/perryrh0dan/passline/pkg/exporter"
+	"github.com/perryrh0dan/passline/pkg/storage"
+)
+
+func TestReadCSV(t *testing.T) {
+	export := "url,username,password,extra,name,grouping,fav\n" +
+		"https://www.github.com/login,perry,secret,,GitHub,,0\n" +
+		"http://sn,,,note,Note,,0\n" +
+		"https://github.com,other,secret2,,GitHub,,0\n"
+
+	entries, err := readCSV(strings.NewReader(export), columns{
+		title:    []string{"name"},
+		url:      []string{"url"},
+		username: []string{"username"},
+		password: []string{"password"},
+		skip:     func(e entry) bool { return e.url == "http://sn" },
+	})
+	if err != nil {
+		t.Fatalf("readCSV() error: %v", err)
+	}
+
+	items := group(entries)
+	if len(items) != 1 || items[0].Name != "github.com" || len(items[0].Credentials) != 2 {
+		t.Errorf("group() = %+v; wanted one item github.com with two credentials", items)
+	}
+}
+
+func TestParsePassFile(t *testing.T) {
+	e := parsePassFile("hunter2\nlogin: perry\nurl: https://example.com\n")
+	if e.password != "hunter2" || e.username != "perry" || e.url != "https://example.com" {
+		t.Errorf("parsePassFile() = %+v", e)
+	}
+}
+
+func TestBitwarden(t *testing.T) {
+	dir, err := ioutil.TempDir("", "passline-bitwarden")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	uris := []storage.URI{
+		{URI: "github.com"},
+		{URI: "https://github.com:8443", Match: storage.MatchHost},
+		{URI: "https://github.com/login", Match: storage.MatchExact},
+		{URI: `^https://gist\.github\.com/`, Match: storage.MatchRegex},
+	}
+	items := []storage.Item{{Name: "GitHub", Folder: "dev/code", URIs: uris, Credentials: []storage.Credential{{Username: "perry", Password: "secret"}}}}
+
+	// Folders and urls survive an export and import
+	var buf bytes.Buffer
+	err = exporter.Write("bitwarden", &buf, items)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "export.json")
+	_ = ioutil.WriteFile(path, buf.Bytes(), 0600)
+
+	imported, err := Parse("bitwarden", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(imported) != 1 || imported[0].Folder != "dev/code" || storage.FormatURIs(imported[0].URIs) != storage.FormatURIs(uris) {
+		t.Errorf("Parse(bitwarden) of an export = %+v", imported)
+	}
+
+	// Starts with is a regex, never matched uris are skipped
+	export := `{"encrypted": false, "folders": [], "items": [{"type": 1, "name": "GitHub", "folderId": null, "login": {"username": "perry", "password": "secret", "uris": [
+		{"match": 2, "uri": "https://github.com/login"},
+		{"match": 5, "uri": "https://github.com/never"}
+	]}}]}`
+	_ = ioutil.WriteFile(path, []byte(export), 0600)
+
+	imported, err = Parse("bitwarden", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `regex:^https://github\.com/login`
+	if len(imported) != 1 || imported[0].Name != "github.com" || storage.FormatURIs(imported[0].URIs) != want {
+		t.Errorf("Parse(bitwarden) = %+v; wanted the uri %s", imported, want)
+	}
+}
@@ -0,0 +1,79 @@
+package importer
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+
+	"github.com/perryrh0dan/passline/pkg/storage"
+)
+
+type keePassFile struct {
+	Root struct {
+		Groups []keePassGroup `xml:"Group"`
+	} `xml:"Root"`
+}
+
+type keePassGroup struct {
+	Name    string         `xml:"Name"`
+	Entries []keePassEntry `xml:"Entry"`
+	Groups  []keePassGroup `xml:"Group"`
+}
+
+type keePassEntry struct {
+	Strings []struct {
+		Key   string `xml:"Key"`
+		Value string `xml:"Value"`
+	} `xml:"String"`
+}
+
+func (e keePassEntry) get(key string) string {
+	for _, s := range e.Strings {
+		if s.Key == key {
+			return s.Value
+		}
+	}
+
+	return ""
+}
+
+func parseKeePassXML(path string) ([]storage.Item, error) {
+	file, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	data := keePassFile{}
+	err = xml.Unmarshal(file, &data)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []entry{}
+	for _, g := range data.Root.Groups {
+		entries = append(entries, keePassEntries(g)...)
+	}
+
+	return group(entries), nil
+}
+
+func keePassEntries(g keePassGroup) []entry {
+	entries := []entry{}
+	for _, e := range g.Entries {
+		entries = append(entries, entry{
+			title:    e.get("Title"),
+			url:      e.get("URL"),
+			username: e.get("UserName"),
+			password: e.get("Password"),
+		})
+	}
+
+	for _, sub := range g.Groups {
+		// The recycle bin holds deleted entries
+		if sub.Name == "Recycle Bin" {
+			continue
+		}
+		entries = append(entries, keePassEntries(sub)...)
+	}
+
+	return entries
+}
@@ -0,0 +1,85 @@
+package importer
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/perryrh0dan/passline/pkg/storage"
+)
+
+var passUsernameKeys = []string{"login:", "username:", "user:", "email:"}
+
+// parsePass reads a password-store directory. Every .gpg file is decrypted
+// with gpg. Files in a subdirectory are read as <name>/<username>.gpg, files
+// in the root as <name>.gpg with the username taken from a login line.
+func parsePass(root string) ([]storage.Item, error) {
+	entries := []entry{}
+
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			if strings.HasPrefix(info.Name(), ".") && path != root {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if filepath.Ext(path) != ".gpg" {
+			return nil
+		}
+
+		content, err := exec.Command("gpg", "--quiet", "--batch", "--decrypt", path).Output()
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(root, strings.TrimSuffix(path, ".gpg"))
+		if err != nil {
+			return err
+		}
+
+		e := parsePassFile(string(content))
+		dir, base := filepath.Split(filepath.ToSlash(rel))
+		if dir == "" {
+			e.title = base
+		} else {
+			e.title = filepath.Base(strings.TrimSuffix(dir, "/"))
+			if e.username == "" {
+				e.username = base
+			}
+		}
+
+		entries = append(entries, e)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return group(entries), nil
+}
+
+func parsePassFile(content string) entry {
+	lines := strings.Split(strings.Replace(content, "\r\n", "\n", -1), "\n")
+
+	e := entry{password: lines[0]}
+	for _, line := range lines[1:] {
+		lower := strings.ToLower(line)
+		for _, key := range passUsernameKeys {
+			if e.username == "" && strings.HasPrefix(lower, key) {
+				e.username = strings.TrimSpace(line[len(key):])
+			}
+		}
+
+		if e.url == "" && strings.HasPrefix(lower, "url:") {
+			e.url = strings.TrimSpace(line[len("url:"):])
+		}
+	}
+
+	return e
+}
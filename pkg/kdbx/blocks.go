@@ -0,0 +1,87 @@
+package kdbx
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+const blockSize = 1024 * 1024
+
+// readBlocks reads the hmac protected block stream that follows the header
+func readBlocks(r io.Reader, hmacKey []byte) ([]byte, error) {
+	var payload bytes.Buffer
+
+	for index := uint64(0); ; index++ {
+		var mac [32]byte
+		_, err := io.ReadFull(r, mac[:])
+		if err != nil {
+			return nil, err
+		}
+
+		var size uint32
+		err = binary.Read(r, binary.LittleEndian, &size)
+		if err != nil {
+			return nil, err
+		}
+
+		data := make([]byte, size)
+		_, err = io.ReadFull(r, data)
+		if err != nil {
+			return nil, err
+		}
+
+		if !hmac.Equal(mac[:], blockHmac(hmacKey, index, blockData(index, data))) {
+			return nil, errors.New("Corrupted database block")
+		}
+
+		if size == 0 {
+			return payload.Bytes(), nil
+		}
+		payload.Write(data)
+	}
+}
+
+func writeBlocks(w *bytes.Buffer, hmacKey []byte, payload []byte) {
+	index := uint64(0)
+	for {
+		n := len(payload)
+		if n > blockSize {
+			n = blockSize
+		}
+
+		data := payload[:n]
+		payload = payload[n:]
+
+		w.Write(blockHmac(hmacKey, index, blockData(index, data)))
+		binary.Write(w, binary.LittleEndian, uint32(len(data)))
+		w.Write(data)
+
+		if n == 0 {
+			return
+		}
+		index++
+	}
+}
+
+// blockData is the input of a block hmac: index, size and data
+func blockData(index uint64, data []byte) []byte {
+	buf := make([]byte, 12, 12+len(data))
+	binary.LittleEndian.PutUint64(buf, index)
+	binary.LittleEndian.PutUint32(buf[8:], uint32(len(data)))
+	return append(buf, data...)
+}
+
+func blockHmac(hmacKey []byte, index uint64, data []byte) []byte {
+	indexBytes := make([]byte, 8)
+	binary.LittleEndian.PutUint64(indexBytes, index)
+	key := sha512.Sum512(append(indexBytes, hmacKey...))
+
+	mac := hmac.New(sha256.New, key[:])
+	mac.Write(data)
+	return mac.Sum(nil)
+}
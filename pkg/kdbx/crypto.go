@@ -0,0 +1,148 @@
+package kdbx
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20"
+	"golang.org/x/crypto/twofish"
+)
+
+var (
+	cipherAES256   = []byte{0x31, 0xc1, 0xf2, 0xe6, 0xbf, 0x71, 0x43, 0x50, 0xbe, 0x58, 0x05, 0x21, 0x6a, 0xfc, 0x5a, 0xff}
+	cipherChaCha20 = []byte{0xd6, 0x03, 0x8a, 0x2b, 0x8b, 0x6f, 0x4c, 0xb5, 0xa5, 0x24, 0x33, 0x9a, 0x31, 0xdb, 0xb5, 0x9a}
+	cipherTwofish  = []byte{0xad, 0x68, 0xf2, 0x9f, 0x57, 0x6f, 0x4b, 0xb9, 0xa3, 0x6a, 0xd4, 0x7a, 0xf9, 0x65, 0x34, 0x6c}
+
+	kdfArgon2d  = []byte{0xef, 0x63, 0x6d, 0xdf, 0x8c, 0x29, 0x44, 0x4b, 0x91, 0xf7, 0xa9, 0xa4, 0x03, 0xe3, 0x0a, 0x0c}
+	kdfArgon2id = []byte{0x9e, 0x29, 0x8b, 0x19, 0x56, 0xdb, 0x47, 0x73, 0xb2, 0x3d, 0xfc, 0x3e, 0xc6, 0xf0, 0xa1, 0xe6}
+	kdfAES      = []byte{0xc9, 0xd9, 0xf3, 0x9a, 0x62, 0x8a, 0x44, 0x60, 0xbf, 0x74, 0x0d, 0x08, 0xc1, 0x8a, 0x4f, 0xea}
+	kdfAES4     = []byte{0x7c, 0x02, 0xbb, 0x82, 0x79, 0xa7, 0x4a, 0xc0, 0x92, 0x7d, 0x11, 0x4a, 0x00, 0x64, 0x82, 0x38}
+)
+
+const streamChaCha20 uint32 = 3
+
+func transformKey(composite []byte, kdf variantDict) ([]byte, error) {
+	uuid := kdf.getBytes("$UUID")
+
+	switch {
+	case bytes.Equal(uuid, kdfArgon2id):
+		if kdf.getUint32("V") != 0x13 {
+			return nil, errors.New("Unsupported argon2 version")
+		}
+
+		memory := kdf.getUint64("M") / 1024
+		key := argon2.IDKey(composite, kdf.getBytes("S"), uint32(kdf.getUint64("I")), uint32(memory), uint8(kdf.getUint32("P")), 32)
+		return key, nil
+	case bytes.Equal(uuid, kdfAES), bytes.Equal(uuid, kdfAES4):
+		block, err := aes.NewCipher(kdf.getBytes("S"))
+		if err != nil {
+			return nil, err
+		}
+
+		key := append([]byte{}, composite...)
+		for i := uint64(0); i < kdf.getUint64("R"); i++ {
+			block.Encrypt(key[:16], key[:16])
+			block.Encrypt(key[16:], key[16:])
+		}
+
+		sum := sha256.Sum256(key)
+		return sum[:], nil
+	case bytes.Equal(uuid, kdfArgon2d):
+		return nil, errors.New("Argon2d is not supported, change the key derivation function to Argon2id or AES-KDF")
+	}
+
+	return nil, errors.New("Unsupported key derivation function")
+}
+
+func ivSize(cipherID []byte) int {
+	if bytes.Equal(cipherID, cipherChaCha20) {
+		return 12
+	}
+
+	return 16
+}
+
+func newBlockCipher(cipherID, key []byte) (cipher.Block, error) {
+	switch {
+	case bytes.Equal(cipherID, cipherAES256):
+		return aes.NewCipher(key)
+	case bytes.Equal(cipherID, cipherTwofish):
+		return twofish.NewCipher(key)
+	}
+
+	return nil, errors.New("Unsupported cipher")
+}
+
+func decrypt(cipherID, key, iv, data []byte) ([]byte, error) {
+	if bytes.Equal(cipherID, cipherChaCha20) {
+		stream, err := chacha20.NewUnauthenticatedCipher(key, iv)
+		if err != nil {
+			return nil, err
+		}
+
+		plaintext := make([]byte, len(data))
+		stream.XORKeyStream(plaintext, data)
+		return plaintext, nil
+	}
+
+	block, err := newBlockCipher(cipherID, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) == 0 || len(data)%block.BlockSize() != 0 {
+		return nil, errors.New("Invalid payload length")
+	}
+
+	plaintext := make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plaintext, data)
+
+	// Remove PKCS7 padding
+	padding := int(plaintext[len(plaintext)-1])
+	if padding == 0 || padding > block.BlockSize() {
+		return nil, errors.New("Invalid padding")
+	}
+
+	return plaintext[:len(plaintext)-padding], nil
+}
+
+func encrypt(cipherID, key, iv, data []byte) ([]byte, error) {
+	if bytes.Equal(cipherID, cipherChaCha20) {
+		stream, err := chacha20.NewUnauthenticatedCipher(key, iv)
+		if err != nil {
+			return nil, err
+		}
+
+		ciphertext := make([]byte, len(data))
+		stream.XORKeyStream(ciphertext, data)
+		return ciphertext, nil
+	}
+
+	block, err := newBlockCipher(cipherID, key)
+	if err != nil {
+		return nil, err
+	}
+
+	// Add PKCS7 padding
+	padding := block.BlockSize() - len(data)%block.BlockSize()
+	data = append(append([]byte{}, data...), bytes.Repeat([]byte{byte(padding)}, padding)...)
+
+	ciphertext := make([]byte, len(data))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext, data)
+	return ciphertext, nil
+}
+
+// newInnerStream creates the cipher for protected values in the xml content
+func newInnerStream(id, key []byte) (cipher.Stream, error) {
+	if len(id) != 4 || id[0] != byte(streamChaCha20) {
+		return nil, errors.New("Unsupported inner stream cipher")
+	}
+
+	hash := sha512.Sum512(key)
+	return chacha20.NewUnauthenticatedCipher(hash[:32], hash[32:44])
+}
@@ -0,0 +1,284 @@
+package kdbx
+
+import (
+	"encoding/xml"
+	"strings"
+	"time"
+)
+
+// Entry is a KeePass entry with its group
+type Entry struct {
+	node  *Node
+	group *Node
+	path  string
+}
+
+// Entries returns all entries outside of the recycle bin in document order
+func (db *Database) Entries() []*Entry {
+	group := db.rootGroup()
+	if group == nil {
+		return []*Entry{}
+	}
+
+	recycleBin := ""
+	if meta := db.Content.Child("Meta"); meta != nil {
+		recycleBin = meta.ChildText("RecycleBinUUID")
+	}
+
+	return collectEntries(group, "", recycleBin)
+}
+
+func collectEntries(group *Node, path, recycleBin string) []*Entry {
+	entries := []*Entry{}
+	for _, node := range group.Children("Entry") {
+		entries = append(entries, &Entry{node: node, group: group, path: path})
+	}
+
+	for _, sub := range group.Children("Group") {
+		if recycleBin != "" && sub.ChildText("UUID") == recycleBin {
+			continue
+		}
+		entries = append(entries, collectEntries(sub, joinPath(path, sub.ChildText("Name")), recycleBin)...)
+	}
+
+	return entries
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+
+	return path + "/" + name
+}
+
+func (db *Database) rootGroup() *Node {
+	root := db.Content.Child("Root")
+	if root == nil {
+		return nil
+	}
+
+	return root.Child("Group")
+}
+
+// AddEntry adds an empty entry to the root group
+func (db *Database) AddEntry() *Entry {
+	group := db.rootGroup()
+	if group == nil {
+		root := db.Content.Child("Root")
+		if root == nil {
+			root = newNode("Root", "")
+			db.Content.Nodes = append(db.Content.Nodes, root)
+		}
+
+		group = newGroup("Passline")
+		root.Nodes = append([]*Node{group}, root.Nodes...)
+	}
+
+	node := newNode("Entry", "")
+	node.Nodes = []*Node{newNode("UUID", newUUID()), newNode("IconID", "0"), newTimes(time.Now())}
+	insertEntry(group, node)
+
+	return &Entry{node: node, group: group}
+}
+
+// MoveEntry moves the entry into the group with the given path below the
+// root group, missing groups are created
+func (db *Database) MoveEntry(entry *Entry, path string) {
+	if entry.path == path {
+		return
+	}
+
+	group := db.rootGroup()
+	for _, name := range strings.Split(path, "/") {
+		if name == "" {
+			continue
+		}
+
+		var sub *Node
+		for _, child := range group.Children("Group") {
+			if child.ChildText("Name") == name {
+				sub = child
+				break
+			}
+		}
+
+		if sub == nil {
+			sub = newGroup(name)
+			group.Nodes = append(group.Nodes, sub)
+		}
+		group = sub
+	}
+
+	entry.group.remove(entry.node)
+	insertEntry(group, entry.node)
+	entry.group = group
+	entry.path = path
+
+	if times := entry.node.Child("Times"); times != nil {
+		times.setChild("LocationChanged", formatTime(time.Now()))
+	}
+}
+
+func newGroup(name string) *Node {
+	group := newNode("Group", "")
+	group.Nodes = []*Node{newNode("UUID", newUUID()), newNode("Name", name), newTimes(time.Now())}
+	return group
+}
+
+// insertEntry adds the entry node to the group, entries are placed before
+// sub groups
+func insertEntry(group *Node, node *Node) {
+	index := len(group.Nodes)
+	for i, child := range group.Nodes {
+		if child.XMLName.Local == "Group" {
+			index = i
+			break
+		}
+	}
+	group.Nodes = append(group.Nodes[:index], append([]*Node{node}, group.Nodes[index:]...)...)
+}
+
+// RemoveEntry deletes the entry and records it as deleted object
+func (db *Database) RemoveEntry(entry *Entry) {
+	entry.group.remove(entry.node)
+
+	root := db.Content.Child("Root")
+	deleted := root.Child("DeletedObjects")
+	if deleted == nil {
+		deleted = newNode("DeletedObjects", "")
+		root.Nodes = append(root.Nodes, deleted)
+	}
+
+	object := newNode("DeletedObject", "")
+	object.Nodes = []*Node{newNode("UUID", entry.node.ChildText("UUID")), newNode("DeletionTime", formatTime(time.Now()))}
+	deleted.Nodes = append(deleted.Nodes, object)
+}
+
+// Path returns the names of the groups between the root group and the entry
+// joined by slashes
+func (e *Entry) Path() string {
+	return e.path
+}
+
+// Tags returns the tags of the entry
+func (e *Entry) Tags() []string {
+	tags := []string{}
+	for _, tag := range strings.FieldsFunc(e.node.ChildText("Tags"), func(r rune) bool { return r == ';' || r == ',' }) {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+
+	return tags
+}
+
+// SetTags replaces the tags of the entry
+func (e *Entry) SetTags(tags []string) {
+	value := strings.Join(tags, ";")
+	if e.node.ChildText("Tags") == value {
+		return
+	}
+	e.node.setChild("Tags", value)
+
+	if times := e.node.Child("Times"); times != nil {
+		times.setChild("LastModificationTime", formatTime(time.Now()))
+	}
+}
+
+// Get returns the value of a string field like Title, UserName or Password
+func (e *Entry) Get(key string) string {
+	for _, s := range e.node.Children("String") {
+		if s.ChildText("Key") == key {
+			return s.ChildText("Value")
+		}
+	}
+
+	return ""
+}
+
+// Times returns when the entry was created and last modified
+func (e *Entry) Times() (time.Time, time.Time) {
+	times := e.node.Child("Times")
+	if times == nil {
+		return time.Time{}, time.Time{}
+	}
+
+	return parseTime(times.ChildText("CreationTime")), parseTime(times.ChildText("LastModificationTime"))
+}
+
+// SetTimes changes the creation and modification time, zero times are not
+// changed
+func (e *Entry) SetTimes(created, modified time.Time) {
+	times := e.node.Child("Times")
+	if times == nil {
+		times = newTimes(time.Now())
+		e.node.Nodes = append(e.node.Nodes, times)
+	}
+
+	if !created.IsZero() {
+		times.setChild("CreationTime", formatTime(created))
+	}
+	if !modified.IsZero() {
+		times.setChild("LastModificationTime", formatTime(modified))
+	}
+}
+
+// Keys returns the names of all string fields
+func (e *Entry) Keys() []string {
+	keys := []string{}
+	for _, s := range e.node.Children("String") {
+		keys = append(keys, s.ChildText("Key"))
+	}
+
+	return keys
+}
+
+// Remove deletes a string field
+func (e *Entry) Remove(key string) {
+	for _, s := range e.node.Children("String") {
+		if s.ChildText("Key") == key {
+			e.node.remove(s)
+			if times := e.node.Child("Times"); times != nil {
+				times.setChild("LastModificationTime", formatTime(time.Now()))
+			}
+			return
+		}
+	}
+}
+
+// Set changes a string field and updates the modification time
+func (e *Entry) Set(key, value string, protected bool) {
+	var field *Node
+	for _, s := range e.node.Children("String") {
+		if s.ChildText("Key") == key {
+			field = s
+			break
+		}
+	}
+
+	if field == nil {
+		field = newNode("String", "")
+		field.Nodes = []*Node{newNode("Key", key), newNode("Value", "")}
+		e.node.Nodes = append(e.node.Nodes, field)
+	}
+
+	v := field.Child("Value")
+	if v == nil {
+		v = newNode("Value", "")
+		field.Nodes = append(field.Nodes, v)
+	}
+
+	if v.Text == value {
+		return
+	}
+	v.Text = value
+
+	if protected {
+		v.Attrs = []xml.Attr{{Name: xml.Name{Local: "Protected"}, Value: "True"}}
+	}
+
+	if times := e.node.Child("Times"); times != nil {
+		times.setChild("LastModificationTime", formatTime(time.Now()))
+	}
+}
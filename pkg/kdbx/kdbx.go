@@ -0,0 +1,449 @@
+// Package kdbx reads and writes KeePass KDBX 4 databases.
+package kdbx
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	signature1   uint32 = 0x9AA2D903
+	signature2   uint32 = 0xB54BFB67
+	versionMajor uint32 = 4
+	version      uint32 = 0x00040000
+)
+
+// Outer header field ids
+const (
+	headerEnd              byte = 0
+	headerCipherID         byte = 2
+	headerCompressionFlags byte = 3
+	headerMasterSeed       byte = 4
+	headerEncryptionIV     byte = 7
+	headerKdfParameters    byte = 11
+	headerPublicCustomData byte = 12
+)
+
+// Inner header field ids
+const (
+	innerHeaderEnd       byte = 0
+	innerHeaderStreamID  byte = 1
+	innerHeaderStreamKey byte = 2
+	innerHeaderBinary    byte = 3
+)
+
+// ErrInvalidCredentials is returned if the header hmac does not match
+var ErrInvalidCredentials = errors.New("Invalid password or corrupted database")
+
+type headerField struct {
+	id    byte
+	value []byte
+}
+
+// Database is a decrypted KDBX 4 database
+type Database struct {
+	fields   []headerField
+	binaries [][]byte
+	key      []byte
+	kdf      variantDict
+
+	// Content is the xml document of the database
+	Content *Node
+}
+
+// New creates an empty database protected by password and the content of a
+// key file. Without key file the password alone protects the database.
+func New(password, keyFile []byte) (*Database, error) {
+	salt := make([]byte, 32)
+	_, err := io.ReadFull(rand.Reader, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	kdf := variantDict{}
+	kdf.setBytes("$UUID", kdfArgon2id)
+	kdf.setUint32("V", 0x13)
+	kdf.setBytes("S", salt)
+	kdf.setUint32("P", 2)
+	kdf.setUint64("M", 64*1024*1024)
+	kdf.setUint64("I", 3)
+
+	db := &Database{
+		fields: []headerField{
+			{id: headerCipherID, value: cipherAES256},
+			{id: headerCompressionFlags, value: uint32Bytes(1)},
+			{id: headerKdfParameters, value: kdf.bytes()},
+		},
+		kdf:     kdf,
+		Content: newContent(),
+	}
+
+	db.key, err = transformKey(compositeKey(password, keyFile), kdf)
+	if err != nil {
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// Open reads and decrypts the database at path
+func Open(path string, password, keyFile []byte) (*Database, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return Decode(data, password, keyFile)
+}
+
+// Save encrypts the database and atomically replaces the file at path
+func (db *Database) Save(path string) error {
+	data, err := db.Encode()
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".kdbx")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// Decode decrypts a database
+func Decode(data, password, keyFile []byte) (*Database, error) {
+	r := bytes.NewReader(data)
+
+	var sig [3]uint32
+	err := binary.Read(r, binary.LittleEndian, &sig)
+	if err != nil {
+		return nil, err
+	}
+
+	if sig[0] != signature1 || sig[1] != signature2 {
+		return nil, errors.New("Not a KeePass database")
+	}
+
+	if sig[2]>>16 != versionMajor {
+		return nil, errors.New("Only KDBX 4 databases are supported")
+	}
+
+	db := &Database{}
+	for {
+		field, err := readField(r)
+		if err != nil {
+			return nil, err
+		}
+
+		if field.id == headerEnd {
+			break
+		}
+		db.fields = append(db.fields, field)
+	}
+
+	headerBytes := data[:len(data)-r.Len()]
+
+	var headerHash, headerHmac [32]byte
+	_, err = io.ReadFull(r, headerHash[:])
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = io.ReadFull(r, headerHmac[:])
+	if err != nil {
+		return nil, err
+	}
+
+	if sha256.Sum256(headerBytes) != headerHash {
+		return nil, errors.New("Corrupted database header")
+	}
+
+	db.kdf, err = parseVariantDict(db.field(headerKdfParameters))
+	if err != nil {
+		return nil, err
+	}
+
+	db.key, err = transformKey(compositeKey(password, keyFile), db.kdf)
+	if err != nil {
+		return nil, err
+	}
+
+	masterSeed := db.field(headerMasterSeed)
+	hmacKey := db.hmacKey(masterSeed)
+	if !bytes.Equal(blockHmac(hmacKey, ^uint64(0), headerBytes), headerHmac[:]) {
+		return nil, ErrInvalidCredentials
+	}
+
+	payload, err := readBlocks(r, hmacKey)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := decrypt(db.field(headerCipherID), db.encryptionKey(masterSeed), db.field(headerEncryptionIV), payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if binary.LittleEndian.Uint32(append(db.field(headerCompressionFlags), 0, 0, 0, 0)) == 1 {
+		gz, err := gzip.NewReader(bytes.NewReader(plaintext))
+		if err != nil {
+			return nil, err
+		}
+
+		plaintext, err = ioutil.ReadAll(gz)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	inner := bytes.NewReader(plaintext)
+	var streamID, streamKey []byte
+	for {
+		field, err := readField(inner)
+		if err != nil {
+			return nil, err
+		}
+
+		if field.id == innerHeaderEnd {
+			break
+		}
+
+		switch field.id {
+		case innerHeaderStreamID:
+			streamID = field.value
+		case innerHeaderStreamKey:
+			streamKey = field.value
+		case innerHeaderBinary:
+			db.binaries = append(db.binaries, field.value)
+		}
+	}
+
+	stream, err := newInnerStream(streamID, streamKey)
+	if err != nil {
+		return nil, err
+	}
+
+	db.Content, err = parseContent(inner, stream)
+	if err != nil {
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// Encode encrypts the database with a fresh master seed, iv and inner stream key
+func (db *Database) Encode() ([]byte, error) {
+	masterSeed, err := randomBytes(32)
+	if err != nil {
+		return nil, err
+	}
+
+	cipherID := db.field(headerCipherID)
+	iv, err := randomBytes(ivSize(cipherID))
+	if err != nil {
+		return nil, err
+	}
+
+	streamKey, err := randomBytes(64)
+	if err != nil {
+		return nil, err
+	}
+
+	// Outer header
+	var header bytes.Buffer
+	binary.Write(&header, binary.LittleEndian, []uint32{signature1, signature2, version})
+	for _, field := range db.fields {
+		switch field.id {
+		case headerMasterSeed, headerEncryptionIV:
+			continue
+		}
+		writeField(&header, field.id, field.value)
+	}
+	writeField(&header, headerMasterSeed, masterSeed)
+	writeField(&header, headerEncryptionIV, iv)
+	writeField(&header, headerEnd, []byte("\r\n\r\n"))
+
+	// Inner header and content
+	var inner bytes.Buffer
+	writeField(&inner, innerHeaderStreamID, uint32Bytes(streamChaCha20))
+	writeField(&inner, innerHeaderStreamKey, streamKey)
+	for _, binary := range db.binaries {
+		writeField(&inner, innerHeaderBinary, binary)
+	}
+	writeField(&inner, innerHeaderEnd, nil)
+
+	stream, err := newInnerStream(uint32Bytes(streamChaCha20), streamKey)
+	if err != nil {
+		return nil, err
+	}
+
+	err = writeContent(&inner, db.Content, stream)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext := inner.Bytes()
+	if binary.LittleEndian.Uint32(append(db.field(headerCompressionFlags), 0, 0, 0, 0)) == 1 {
+		var compressed bytes.Buffer
+		gz := gzip.NewWriter(&compressed)
+		_, err = gz.Write(plaintext)
+		if err != nil {
+			return nil, err
+		}
+
+		err = gz.Close()
+		if err != nil {
+			return nil, err
+		}
+		plaintext = compressed.Bytes()
+	}
+
+	payload, err := encrypt(cipherID, db.encryptionKey(masterSeed), iv, plaintext)
+	if err != nil {
+		return nil, err
+	}
+
+	hmacKey := db.hmacKey(masterSeed)
+	headerHash := sha256.Sum256(header.Bytes())
+
+	var out bytes.Buffer
+	out.Write(header.Bytes())
+	out.Write(headerHash[:])
+	out.Write(blockHmac(hmacKey, ^uint64(0), header.Bytes()))
+	writeBlocks(&out, hmacKey, payload)
+
+	return out.Bytes(), nil
+}
+
+func (db *Database) field(id byte) []byte {
+	for _, field := range db.fields {
+		if field.id == id {
+			return field.value
+		}
+	}
+
+	return nil
+}
+
+func (db *Database) encryptionKey(masterSeed []byte) []byte {
+	sum := sha256.Sum256(append(append([]byte{}, masterSeed...), db.key...))
+	return sum[:]
+}
+
+func (db *Database) hmacKey(masterSeed []byte) []byte {
+	data := append(append([]byte{}, masterSeed...), db.key...)
+	sum := sha512.Sum512(append(data, 1))
+	return sum[:]
+}
+
+func compositeKey(password, keyFile []byte) []byte {
+	hash := sha256.Sum256(password)
+	parts := hash[:]
+	if keyFile != nil {
+		parts = append(parts, keyFileKey(keyFile)...)
+	}
+
+	composite := sha256.Sum256(parts)
+	return composite[:]
+}
+
+// keyFileKey reads the key of a key file the way KeePass does. XML key files
+// contain the key, 32 bytes are the key itself and 64 hex characters are the
+// encoded key. The hash of any other file is the key.
+func keyFileKey(data []byte) []byte {
+	var file struct {
+		Version string `xml:"Meta>Version"`
+		Data    string `xml:"Key>Data"`
+	}
+	if xml.Unmarshal(data, &file) == nil && file.Data != "" {
+		var key []byte
+		var err error
+		if strings.HasPrefix(file.Version, "2.") {
+			key, err = hex.DecodeString(strings.Join(strings.Fields(file.Data), ""))
+		} else {
+			key, err = base64.StdEncoding.DecodeString(strings.TrimSpace(file.Data))
+		}
+		if err == nil && len(key) == 32 {
+			return key
+		}
+	}
+
+	if len(data) == 32 {
+		return data
+	}
+
+	if len(data) == 64 {
+		if key, err := hex.DecodeString(string(data)); err == nil {
+			return key
+		}
+	}
+
+	hash := sha256.Sum256(data)
+	return hash[:]
+}
+
+func readField(r io.Reader) (headerField, error) {
+	var id [1]byte
+	_, err := io.ReadFull(r, id[:])
+	if err != nil {
+		return headerField{}, err
+	}
+
+	var length uint32
+	err = binary.Read(r, binary.LittleEndian, &length)
+	if err != nil {
+		return headerField{}, err
+	}
+
+	value := make([]byte, length)
+	_, err = io.ReadFull(r, value)
+	if err != nil {
+		return headerField{}, err
+	}
+
+	return headerField{id: id[0], value: value}, nil
+}
+
+func writeField(w *bytes.Buffer, id byte, value []byte) {
+	w.WriteByte(id)
+	binary.Write(w, binary.LittleEndian, uint32(len(value)))
+	w.Write(value)
+}
+
+func uint32Bytes(v uint32) []byte {
+	b := make([]byte, 4)
+	binary.LittleEndian.PutUint32(b, v)
+	return b
+}
+
+func randomBytes(n int) ([]byte, error) {
+	b := make([]byte, n)
+	_, err := io.ReadFull(rand.Reader, b)
+	return b, err
+}
@@ -0,0 +1,96 @@
+package kdbx
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"testing"
+	"time"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	password := []byte("1234567891011123")
+
+	db, err := New(password, nil)
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+
+	entry := db.AddEntry()
+	entry.Set("Title", "github.com", false)
+	entry.Set("UserName", "perry", false)
+	entry.Set("Password", "secret", true)
+	entry.SetTags([]string{"work", "oss"})
+	db.MoveEntry(entry, "dev/code")
+	created := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	entry.SetTimes(created, time.Time{})
+
+	data, err := db.Encode()
+	if err != nil {
+		t.Fatalf("Encode() error: %v", err)
+	}
+
+	if entry.Get("Password") != "secret" {
+		t.Errorf("Encode() changed the plaintext of protected values")
+	}
+
+	decoded, err := Decode(data, password, nil)
+	if err != nil {
+		t.Fatalf("Decode() error: %v", err)
+	}
+
+	entries := decoded.Entries()
+	if len(entries) != 1 || entries[0].Get("UserName") != "perry" || entries[0].Get("Password") != "secret" {
+		t.Errorf("Decode() returned unexpected entries")
+	}
+
+	if len(entries) == 1 && (entries[0].Path() != "dev/code" || len(entries[0].Tags()) != 2) {
+		t.Errorf("Decode() returned entry in %q with tags %v; wanted dev/code, [work oss]", entries[0].Path(), entries[0].Tags())
+	}
+
+	if len(entries) == 1 {
+		if c, _ := entries[0].Times(); !c.Equal(created) {
+			t.Errorf("Decode() returned entry created at %v; wanted %v", c, created)
+		}
+	}
+
+	_, err = Decode(data, []byte("wrong"), nil)
+	if err != ErrInvalidCredentials {
+		t.Errorf("Decode() with wrong password = %v; wanted %v", err, ErrInvalidCredentials)
+	}
+}
+
+func TestKeyFile(t *testing.T) {
+	password := []byte("1234567891011123")
+	key := []byte("12345678901234567890123456789012")
+
+	db, err := New(password, key)
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+
+	data, err := db.Encode()
+	if err != nil {
+		t.Fatalf("Encode() error: %v", err)
+	}
+
+	// The key can be stored raw, hex encoded or in a KeePass XML key file
+	keyFiles := map[string][]byte{
+		"raw": key,
+		"hex": []byte(hex.EncodeToString(key)),
+		"xml v1": []byte(`<KeyFile><Meta><Version>1.00</Version></Meta><Key><Data>` +
+			base64.StdEncoding.EncodeToString(key) + `</Data></Key></KeyFile>`),
+		"xml v2": []byte(`<KeyFile><Meta><Version>2.0</Version></Meta><Key><Data Hash="00000000">` +
+			hex.EncodeToString(key[:16]) + "\n" + hex.EncodeToString(key[16:]) + `</Data></Key></KeyFile>`),
+	}
+
+	for name, keyFile := range keyFiles {
+		if _, err := Decode(data, password, keyFile); err != nil {
+			t.Errorf("Decode() with %s key file error: %v", name, err)
+		}
+	}
+
+	_, err = Decode(data, password, nil)
+	if err != ErrInvalidCredentials {
+		t.Errorf("Decode() without key file = %v; wanted %v", err, ErrInvalidCredentials)
+	}
+}
@@ -0,0 +1,153 @@
+package kdbx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+const variantDictVersion uint16 = 0x0100
+
+// Variant dictionary value types
+const (
+	variantUInt32    byte = 0x04
+	variantUInt64    byte = 0x05
+	variantByteArray byte = 0x42
+)
+
+type variantEntry struct {
+	kind  byte
+	key   string
+	value []byte
+}
+
+// variantDict holds the kdf parameters in their original order
+type variantDict []variantEntry
+
+func parseVariantDict(data []byte) (variantDict, error) {
+	r := bytes.NewReader(data)
+
+	var version uint16
+	err := binary.Read(r, binary.LittleEndian, &version)
+	if err != nil {
+		return nil, err
+	}
+
+	if version&0xff00 != variantDictVersion&0xff00 {
+		return nil, errors.New("Unsupported variant dictionary version")
+	}
+
+	dict := variantDict{}
+	for {
+		kind, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+
+		if kind == 0 {
+			return dict, nil
+		}
+
+		key, err := readSized(r)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := readSized(r)
+		if err != nil {
+			return nil, err
+		}
+
+		dict = append(dict, variantEntry{kind: kind, key: string(key), value: value})
+	}
+}
+
+func readSized(r io.Reader) ([]byte, error) {
+	var size int32
+	err := binary.Read(r, binary.LittleEndian, &size)
+	if err != nil {
+		return nil, err
+	}
+
+	if size < 0 {
+		return nil, errors.New("Invalid variant dictionary")
+	}
+
+	data := make([]byte, size)
+	_, err = io.ReadFull(r, data)
+	return data, err
+}
+
+func (d variantDict) bytes() []byte {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, variantDictVersion)
+
+	for _, entry := range d {
+		buf.WriteByte(entry.kind)
+		binary.Write(&buf, binary.LittleEndian, int32(len(entry.key)))
+		buf.WriteString(entry.key)
+		binary.Write(&buf, binary.LittleEndian, int32(len(entry.value)))
+		buf.Write(entry.value)
+	}
+
+	buf.WriteByte(0)
+	return buf.Bytes()
+}
+
+func (d variantDict) get(key string) []byte {
+	for _, entry := range d {
+		if entry.key == key {
+			return entry.value
+		}
+	}
+
+	return nil
+}
+
+func (d *variantDict) set(kind byte, key string, value []byte) {
+	for i, entry := range *d {
+		if entry.key == key {
+			(*d)[i] = variantEntry{kind: kind, key: key, value: value}
+			return
+		}
+	}
+
+	*d = append(*d, variantEntry{kind: kind, key: key, value: value})
+}
+
+func (d variantDict) getBytes(key string) []byte {
+	return d.get(key)
+}
+
+func (d variantDict) getUint32(key string) uint32 {
+	value := d.get(key)
+	if len(value) != 4 {
+		return 0
+	}
+
+	return binary.LittleEndian.Uint32(value)
+}
+
+func (d variantDict) getUint64(key string) uint64 {
+	value := d.get(key)
+	if len(value) != 8 {
+		return 0
+	}
+
+	return binary.LittleEndian.Uint64(value)
+}
+
+func (d *variantDict) setBytes(key string, value []byte) {
+	d.set(variantByteArray, key, value)
+}
+
+func (d *variantDict) setUint32(key string, value uint32) {
+	d.set(variantUInt32, key, uint32Bytes(value))
+}
+
+func (d *variantDict) setUint64(key string, value uint64) {
+	b := make([]byte, 8)
+	binary.LittleEndian.PutUint64(b, value)
+	d.set(variantUInt64, key, b)
+}
@@ -0,0 +1,230 @@
+package kdbx
+
+import (
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/xml"
+	"io"
+	"strings"
+	"time"
+)
+
+// Node is a generic xml element. Decoding the content into nodes keeps
+// everything passline does not know about when the database is saved.
+type Node struct {
+	XMLName xml.Name
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Text    string     `xml:",chardata"`
+	Nodes   []*Node    `xml:",any"`
+}
+
+// secondsToUnix is the offset between 0001-01-01 and the unix epoch
+const secondsToUnix = 62135596800
+
+func newNode(name, text string) *Node {
+	return &Node{XMLName: xml.Name{Local: name}, Text: text}
+}
+
+// Child returns the first child element with the given name
+func (n *Node) Child(name string) *Node {
+	for _, child := range n.Nodes {
+		if child.XMLName.Local == name {
+			return child
+		}
+	}
+
+	return nil
+}
+
+// Children returns all child elements with the given name
+func (n *Node) Children(name string) []*Node {
+	children := []*Node{}
+	for _, child := range n.Nodes {
+		if child.XMLName.Local == name {
+			children = append(children, child)
+		}
+	}
+
+	return children
+}
+
+// ChildText returns the text of the first child element with the given name
+func (n *Node) ChildText(name string) string {
+	child := n.Child(name)
+	if child == nil {
+		return ""
+	}
+
+	return child.Text
+}
+
+func (n *Node) attr(name string) string {
+	for _, attr := range n.Attrs {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+
+	return ""
+}
+
+func (n *Node) setChild(name, text string) {
+	child := n.Child(name)
+	if child == nil {
+		n.Nodes = append(n.Nodes, newNode(name, text))
+		return
+	}
+
+	child.Text = text
+}
+
+func (n *Node) remove(child *Node) {
+	for i, c := range n.Nodes {
+		if c == child {
+			n.Nodes = append(n.Nodes[:i], n.Nodes[i+1:]...)
+			return
+		}
+	}
+}
+
+// walk calls fn for n and all descendants in document order
+func (n *Node) walk(fn func(*Node)) {
+	fn(n)
+	for _, child := range n.Nodes {
+		child.walk(fn)
+	}
+}
+
+func isProtected(n *Node) bool {
+	return n.XMLName.Local == "Value" && strings.EqualFold(n.attr("Protected"), "True")
+}
+
+func parseContent(r io.Reader, stream cipher.Stream) (*Node, error) {
+	root := &Node{}
+	err := xml.NewDecoder(r).Decode(root)
+	if err != nil {
+		return nil, err
+	}
+
+	var walkErr error
+	root.walk(func(n *Node) {
+		if len(n.Nodes) > 0 && strings.TrimSpace(n.Text) == "" {
+			n.Text = ""
+		}
+
+		if isProtected(n) && walkErr == nil {
+			data, err := base64.StdEncoding.DecodeString(n.Text)
+			if err != nil {
+				walkErr = err
+				return
+			}
+
+			stream.XORKeyStream(data, data)
+			n.Text = string(data)
+		}
+	})
+
+	return root, walkErr
+}
+
+func writeContent(w io.Writer, root *Node, stream cipher.Stream) error {
+	// Protect values for writing and restore the plaintext afterwards
+	protected := []*Node{}
+	plaintexts := []string{}
+	root.walk(func(n *Node) {
+		if isProtected(n) {
+			data := []byte(n.Text)
+			stream.XORKeyStream(data, data)
+
+			protected = append(protected, n)
+			plaintexts = append(plaintexts, n.Text)
+			n.Text = base64.StdEncoding.EncodeToString(data)
+		}
+	})
+
+	defer func() {
+		for i, n := range protected {
+			n.Text = plaintexts[i]
+		}
+	}()
+
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "\t")
+	return encoder.Encode(root)
+}
+
+func formatTime(t time.Time) string {
+	b := make([]byte, 8)
+	binary.LittleEndian.PutUint64(b, uint64(t.Unix()+secondsToUnix))
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+// parseTime reads the base64 encoded seconds of KDBX 4 and the ISO 8601
+// times of older files
+func parseTime(text string) time.Time {
+	if t, err := time.Parse(time.RFC3339, text); err == nil {
+		return t.UTC()
+	}
+
+	b, err := base64.StdEncoding.DecodeString(text)
+	if err != nil || len(b) != 8 {
+		return time.Time{}
+	}
+
+	return time.Unix(int64(binary.LittleEndian.Uint64(b))-secondsToUnix, 0).UTC()
+}
+
+func newTimes(t time.Time) *Node {
+	now := formatTime(t)
+	times := newNode("Times", "")
+	times.Nodes = []*Node{
+		newNode("CreationTime", now),
+		newNode("LastModificationTime", now),
+		newNode("LastAccessTime", now),
+		newNode("ExpiryTime", now),
+		newNode("Expires", "False"),
+		newNode("UsageCount", "0"),
+		newNode("LocationChanged", now),
+	}
+
+	return times
+}
+
+func newUUID() string {
+	uuid, _ := randomBytes(16)
+	return base64.StdEncoding.EncodeToString(uuid)
+}
+
+func newContent() *Node {
+	now := time.Now()
+
+	meta := newNode("Meta", "")
+	meta.Nodes = []*Node{
+		newNode("Generator", "Passline"),
+		newNode("DatabaseName", "Passline"),
+		newNode("DatabaseNameChanged", formatTime(now)),
+		newNode("RecycleBinEnabled", "False"),
+	}
+
+	group := newNode("Group", "")
+	group.Nodes = []*Node{
+		newNode("UUID", newUUID()),
+		newNode("Name", "Passline"),
+		newNode("IconID", "48"),
+		newTimes(now),
+		newNode("IsExpanded", "True"),
+	}
+
+	root := newNode("Root", "")
+	root.Nodes = []*Node{group, newNode("DeletedObjects", "")}
+
+	content := newNode("KeePassFile", "")
+	content.Nodes = []*Node{meta, root}
+	return content
+}
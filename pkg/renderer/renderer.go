@@ -2,9 +2,12 @@ package renderer
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/fatih/color"
+	"github.com/perryrh0dan/passline/pkg/config"
+	"github.com/perryrh0dan/passline/pkg/server"
 	"github.com/perryrh0dan/passline/pkg/storage"
 	"github.com/perryrh0dan/passline/pkg/util"
 )
@@ -24,12 +27,351 @@ func DisplayCredential(credential storage.Credential) {
 	fmt.Printf("Recovery codes: %s\n", util.ArrayToString(credential.RecoveryCodes))
 }
 
+// DisplayItems lists the items grouped by folder with their tags
 func DisplayItems(websites []storage.Item) {
-	for _, website := range websites {
-		fmt.Printf("%s\n", website.Name)
+	sorted := append([]storage.Item{}, websites...)
+	sort.Sort(storage.ByFolder(sorted))
+
+	folder := ""
+	for _, website := range sorted {
+		indent := ""
+		if website.Folder != "" {
+			if website.Folder != folder {
+				folder = website.Folder
+				color.New(color.FgBlue).Printf("%s/\n", folder)
+			}
+			indent = "  "
+		}
+
+		tags := ""
+		if len(website.Tags) > 0 {
+			tags = " " + color.HiBlackString("#"+strings.Join(website.Tags, " #"))
+		}
+
+		fmt.Printf("%s%s%s\n", indent, website.Name, tags)
+	}
+}
+
+// DisplaySearchResult prints the item name and, if another field matched,
+// the field with the matched characters highlighted
+func DisplaySearchResult(item storage.Item, field, value string, positions []int) {
+	name := item.Name
+	if field == "name" {
+		name = highlight(value, positions)
+	}
+
+	folder := ""
+	if item.Folder != "" {
+		folder = color.HiBlackString(item.Folder + "/")
+	}
+
+	if field == "name" {
+		fmt.Printf("%s%s\n", folder, name)
+		return
+	}
+
+	fmt.Printf("%s%s  %s %s\n", folder, name, color.HiBlackString(field+":"), highlight(value, positions))
+}
+
+// highlight colors the runes of value at the given positions
+func highlight(value string, positions []int) string {
+	matched := map[int]bool{}
+	for _, position := range positions {
+		matched[position] = true
+	}
+
+	d := color.New(color.FgGreen, color.Bold)
+
+	var b strings.Builder
+	for i, r := range []rune(value) {
+		if matched[i] {
+			b.WriteString(d.Sprint(string(r)))
+		} else {
+			b.WriteRune(r)
+		}
+	}
+
+	return b.String()
+}
+
+// DisplayMatches lists the credentials of the items
+func DisplayMatches(items []storage.Item) {
+	for _, item := range items {
+		for _, credential := range item.Credentials {
+			fmt.Printf("%s  %s\n", item.Name, color.YellowString(credential.Username))
+		}
+	}
+}
+
+func AuditIssue(item, username, kind, detail string) {
+	d := color.New(color.FgRed)
+	if kind == "old" || kind == "no-recovery-codes" {
+		d = color.New(color.FgYellow)
+	}
+
+	fmt.Printf("%s  %s  %s %s\n", d.Sprint(kind), item, color.YellowString(username), color.HiBlackString(detail))
+}
+
+func AuditSummary(score, credentials int, counts map[string]int, breachChecked bool) {
+	d := color.New(color.FgGreen)
+	if score < 50 {
+		d = color.New(color.FgRed)
+	} else if score < 80 {
+		d = color.New(color.FgYellow)
+	}
+
+	if len(counts) > 0 {
+		fmt.Println()
+	}
+	d.Printf("Score %d/100 for %d credentials\n", score, credentials)
+
+	for _, kind := range []string{"breached", "reused", "weak", "old", "no-recovery-codes"} {
+		if counts[kind] > 0 {
+			fmt.Printf("  %s: %d\n", kind, counts[kind])
+		}
+	}
+
+	if !breachChecked {
+		fmt.Println(color.HiBlackString("Breached passwords were not checked, set Breach.Directory or Breach.URL in the config"))
+	}
+}
+
+func BreachedPassword(count int) {
+	d := color.New(color.FgRed)
+	d.Printf("Password was seen %d times in data breaches\n", count)
+}
+
+func BreachedPasswordRefused(count int) {
+	d := color.New(color.FgRed)
+	d.Printf("Password was seen %d times in data breaches, choose another one\n", count)
+}
+
+func BreachError(err error) {
+	d := color.New(color.FgRed)
+	d.Printf("Unable to check the password against the breach dataset: %v\n", err)
+}
+
+func DisplayBackups(backups []storage.BackupFile) {
+	for _, backup := range backups {
+		fmt.Printf("%s  %s\n", backup.Date.Format("2006-01-02 15:04:05"), backup.Path)
 	}
 }
 
+func SuccessfulMigrated(count int, storage string) {
+	d := color.New(color.FgGreen)
+	d.Printf("Successful migrated %d items, now using storage %s\n", count, storage)
+}
+
+func SuccessfulImported(imported int, skipped []string) {
+	for _, identifier := range skipped {
+		fmt.Printf("Skipped existing item: %s\n", color.YellowString(identifier))
+	}
+
+	d := color.New(color.FgGreen)
+	d.Printf("Successful imported %d credentials, skipped %d\n", imported, len(skipped))
+}
+
+func SuccessfulExported(path string, count int) {
+	d := color.New(color.FgGreen)
+	d.Printf("Successful exported %d items: %s\n", count, path)
+}
+
+func SuccessfulSynced() {
+	d := color.New(color.FgGreen)
+	d.Printf("Successful synchronized storage\n")
+}
+
+// DisplayConflicts lists the items that were changed locally and remotely
+func DisplayConflicts(conflicts []storage.Conflict) {
+	d := color.New(color.FgYellow)
+	d.Printf("%d conflicting changes were not synchronized:\n", len(conflicts))
+
+	for _, conflict := range conflicts {
+		fmt.Printf("%s  %s  local revision %d: %s, remote revision %d: %s\n",
+			conflict.Date.Format("2006-01-02 15:04:05"), conflict.Name,
+			conflict.LocalRevision, conflictUsernames(conflict.Local),
+			conflict.RemoteRevision, conflictUsernames(conflict.Remote))
+	}
+
+	fmt.Printf("Run sync with --keep-local or --keep-remote to resolve them\n")
+}
+
+func conflictUsernames(item *storage.Item) string {
+	if item == nil {
+		return "deleted"
+	}
+
+	usernames := []string{}
+	for _, credential := range item.Credentials {
+		usernames = append(usernames, credential.Username)
+	}
+
+	return strings.Join(usernames, ", ")
+}
+
+// DisplayBackends lists the available storages and marks the current one
+func DisplayBackends(backends []storage.Backend, current string) {
+	for _, backend := range backends {
+		name := fmt.Sprintf("  %-10s", backend.Name)
+		if backend.Name == current {
+			name = color.GreenString("* %-10s", backend.Name)
+		}
+
+		fmt.Printf("%s %s\n", name, backend.Description)
+
+		settings := backend.Settings
+		if len(settings) > 0 {
+			fmt.Printf("             %s\n", color.HiBlackString(strings.Join(settings, ", ")))
+		}
+	}
+}
+
+// DisplayVaults lists the main and all named vaults and marks the selected one
+func DisplayVaults(cfg *config.Config) {
+	names := []string{config.MainVault}
+	for name := range cfg.Vaults {
+		names = append(names, name)
+	}
+	sort.Strings(names[1:])
+
+	for _, name := range names {
+		storage := cfg.Storage
+		if name != config.MainVault {
+			storage = cfg.Vaults[name].Storage
+			if storage == "" {
+				storage = "storage of main"
+			}
+		}
+
+		line := fmt.Sprintf("  %-12s", name)
+		if name == cfg.Vault {
+			line = color.GreenString("* %-12s", name)
+		}
+
+		suffix := ""
+		if name == cfg.DefaultVault || (name == config.MainVault && cfg.DefaultVault == "") {
+			suffix = " (default)"
+		}
+
+		fmt.Printf("%s %s%s\n", line, storage, suffix)
+	}
+}
+
+func SuccessfulAddedVault(name string) {
+	d := color.New(color.FgGreen)
+	d.Printf("Successful added vault: %s\n", name)
+	fmt.Printf("Use it with --vault %s, the master password is set with the first item\n", name)
+}
+
+func SuccessfulRemovedVault(name string) {
+	d := color.New(color.FgGreen)
+	d.Printf("Successful removed vault: %s, its data was kept\n", name)
+}
+
+func SuccessfulChangedDefaultVault(name string) {
+	d := color.New(color.FgGreen)
+	d.Printf("Successful changed default vault: %s\n", name)
+}
+
+func DisplayMembers(members []storage.Member) {
+	for _, member := range members {
+		fmt.Printf("%-20s %s\n", member.Name, color.HiBlackString(member.PublicKey))
+	}
+}
+
+func DisplayPublicKey(publicKey string) {
+	fmt.Printf("Public key: %s\n", publicKey)
+	fmt.Printf("Send it to a member of the team vault to be added\n")
+}
+
+func SuccessfulGeneratedKeyFile(path string) {
+	d := color.New(color.FgGreen)
+	d.Printf("Successful generated key file: %s\n", path)
+	fmt.Printf("Use it for a vault with passline keyfile set %s\n", path)
+}
+
+func SuccessfulChangedKeyFile(path string) {
+	d := color.New(color.FgGreen)
+	if path == "" {
+		d.Printf("Successful removed the key file, the global password is sufficient\n")
+		return
+	}
+	d.Printf("Successful changed key file: %s\n", path)
+	fmt.Printf("Keep a copy of it, the items can not be decrypted without it\n")
+}
+
+func DisplayAgeRecipient(recipient string) {
+	fmt.Printf("Recipient: %s\n", recipient)
+	fmt.Printf("Add it to Age.Recipients of the vault and run passline age update\n")
+}
+
+func SuccessfulInitializedAge() {
+	d := color.New(color.FgGreen)
+	d.Printf("Successful initialized age vault\n")
+}
+
+func SuccessfulUpdatedAgeRecipients() {
+	d := color.New(color.FgGreen)
+	d.Printf("Successful encrypted the vault key to the recipients\n")
+}
+
+func SuccessfulInitializedTeam() {
+	d := color.New(color.FgGreen)
+	d.Printf("Successful initialized team vault\n")
+}
+
+func SuccessfulAddedMember(name string) {
+	d := color.New(color.FgGreen)
+	d.Printf("Successful added member: %s\n", name)
+}
+
+func SuccessfulRemovedMember(name string) {
+	d := color.New(color.FgGreen)
+	d.Printf("Successful removed member: %s, the vault key was replaced\n", name)
+}
+
+func DisplayFirestoreVaults(vaults []storage.FirestoreVault, current string) {
+	for _, vault := range vaults {
+		name := "  " + vault.Name
+		if vault.Name == current {
+			name = color.GreenString("* " + vault.Name)
+		}
+
+		fmt.Printf("%s  %s\n", vault.Created.Format("2006-01-02 15:04:05"), name)
+	}
+}
+
+func NoFirestoreVaultsMessage() {
+	fmt.Printf("No vaults in the firestore project\n")
+}
+
+func SuccessfulChangedFirestoreVault(name string) {
+	d := color.New(color.FgGreen)
+	if name == "" {
+		d.Printf("Successful changed to the firestore collection without vault\n")
+		return
+	}
+	d.Printf("Successful changed to firestore vault: %s\n", name)
+}
+
+func DisplayDevices(devices []server.Device) {
+	for _, device := range devices {
+		fmt.Printf("%s  %s\n", device.Created.Format("2006-01-02 15:04:05"), device.Name)
+	}
+}
+
+func SuccessfulAddedDevice(name, token string) {
+	d := color.New(color.FgGreen)
+	d.Printf("Successful added device: %s\n", name)
+	fmt.Printf("Token: %s\n", token)
+	fmt.Printf("Set it as Remote.Token in the config of the device, it is only shown once\n")
+}
+
+func SuccessfulRemovedDevice(name string) {
+	d := color.New(color.FgGreen)
+	d.Printf("Successful removed device: %s\n", name)
+}
+
 func SuccessfulCopiedToClipboard(name, username string) {
 	identifier := buildIdentifier(name, username)
 	fmt.Fprintf(color.Output, "Copied Password for %s to clipboard\n", identifier)
@@ -74,12 +416,35 @@ func InvalidFilePath() {
 	fmt.Printf("Invalid file path\n")
 }
 
+func InvalidImportFormat(format string, formats []string) {
+	fmt.Printf("Unknown import format: %s, valid formats are: %s\n", format, strings.Join(formats, ", "))
+}
+
+func InvalidStorage(storage string, storages []string) {
+	fmt.Printf("Unknown storage: %s, valid storages are: %s\n", storage, strings.Join(storages, ", "))
+}
+
+func InvalidExportFormat(format string, formats []string) {
+	fmt.Printf("Unknown export format: %s, valid formats are: %s\n", format, strings.Join(formats, ", "))
+}
+
 func ClipboardError() {
 	fmt.Printf("Error occured while copying to clipboard\n")
 }
 
-func CoreInstanceError() {
-	fmt.Printf("Error occured while instantiating core\n")
+func AutoBackupError(err error) {
+	d := color.New(color.FgRed)
+	d.Printf("error: automatic backup failed: %v\n", err)
+}
+
+func UnlockError(err error) {
+	d := color.New(color.FgRed)
+	d.Printf("Unable to unlock vault: %v\n", err)
+}
+
+func VaultError(err error) {
+	d := color.New(color.FgRed)
+	d.Printf("Unable to select vault: %v\n", err)
 }
 
 func StorageError() {
@@ -92,6 +457,31 @@ func NoItemsMessage() {
 	d.Printf("No items yet\n")
 }
 
+func InvalidURL(url string, err error) {
+	d := color.New(color.FgRed)
+	d.Printf("Invalid url %s: %v\n", url, err)
+}
+
+func NoMatchingItemsMessage() {
+	d := color.New(color.FgYellow)
+	d.Printf("No items match the filter\n")
+}
+
+func NoBackupsMessage() {
+	d := color.New(color.FgYellow)
+	d.Printf("No backups yet\n")
+}
+
+func NoDevicesMessage() {
+	d := color.New(color.FgYellow)
+	d.Printf("No devices yet, add one with: passline serve device add <name>\n")
+}
+
+func ServeMessage(address string) {
+	d := color.New(color.FgGreen)
+	d.Printf("Serving passline on %s...\n", address)
+}
+
 func DisplayMessage() {
 	d := color.New(color.FgGreen)
 	d.Printf("Display item...\n")
@@ -122,6 +512,37 @@ func DeleteMessage() {
 	d.Printf("Deleting item...\n")
 }
 
+func ExportMessage() {
+	d := color.New(color.FgGreen)
+	d.Printf("Exporting items...\n")
+}
+
+func ExportWarning(count int) {
+	d := color.New(color.FgRed, color.Bold)
+	d.Printf("WARNING: The export will contain the passwords and recovery codes of %d items in PLAINTEXT.\n", count)
+	d.Printf("Everyone with access to the file can read them. Delete the file as soon as possible.\n")
+}
+
+func ExportAborted() {
+	d := color.New(color.FgYellow)
+	d.Printf("Export aborted\n")
+}
+
+func ImportMessage() {
+	d := color.New(color.FgGreen)
+	d.Printf("Importing items...\n")
+}
+
+func MigrateMessage() {
+	d := color.New(color.FgGreen)
+	d.Printf("Migrating storage...\n")
+}
+
+func SyncMessage() {
+	d := color.New(color.FgGreen)
+	d.Printf("Synchronizing storage...\n")
+}
+
 func RestoreMessage() {
 	d := color.New(color.FgGreen)
 	d.Printf("Restoring backup...\n")
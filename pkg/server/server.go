@@ -0,0 +1,172 @@
+// Package server implements the passline sync server. The server only stores
+// the items as opaque blobs, it never sees the global password.
+package server
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// RemoteItem is an item with its revision as exchanged with the server
+type RemoteItem struct {
+	Name     string          `json:"name"`
+	Revision int64           `json:"revision"`
+	Data     json.RawMessage `json:"data,omitempty"`
+}
+
+// ItemList is the response of listing all items
+type ItemList struct {
+	Revision int64        `json:"revision"`
+	Items    []RemoteItem `json:"items"`
+}
+
+// Server handles the item api
+type Server struct {
+	dir   string
+	store *store
+}
+
+// New creates a server that keeps its data in dir
+func New(dir string) (*Server, error) {
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := openStore(filepath.Join(dir, "store.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Server{dir: dir, store: s}, nil
+}
+
+// ServeHTTP implements the api:
+//
+//	GET    /v1/items         list all items
+//	PUT    /v1/items         replace all items
+//	GET    /v1/items/{name}  get an item
+//	PUT    /v1/items/{name}  create or update an item with its last known revision
+//	DELETE /v1/items/{name}  delete an item with ?revision=n
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	device, ok := authenticate(s.dir, token)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "Invalid token")
+		return
+	}
+
+	path := r.URL.EscapedPath()
+	switch {
+	case path == "/v1/items":
+		s.handleItems(w, r)
+	case strings.HasPrefix(path, "/v1/items/"):
+		name, err := url.PathUnescape(strings.TrimPrefix(path, "/v1/items/"))
+		if err != nil || name == "" {
+			writeError(w, http.StatusBadRequest, "Invalid item name")
+			return
+		}
+		s.handleItem(w, r, name)
+	default:
+		writeError(w, http.StatusNotFound, "Not found")
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		log.Printf("%s %s by %s", r.Method, path, device.Name)
+	}
+}
+
+func (s *Server) handleItems(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		items, revision := s.store.list()
+		writeJSON(w, http.StatusOK, ItemList{Revision: revision, Items: items})
+	case http.MethodPut:
+		list := ItemList{}
+		err := json.NewDecoder(r.Body).Decode(&list)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		revision, err := s.store.replace(list.Items)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		items, _ := s.store.list()
+		writeJSON(w, http.StatusOK, ItemList{Revision: revision, Items: items})
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+func (s *Server) handleItem(w http.ResponseWriter, r *http.Request, name string) {
+	switch r.Method {
+	case http.MethodGet:
+		item, err := s.store.get(name)
+		if err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, item)
+	case http.MethodPut:
+		item := RemoteItem{}
+		err := json.NewDecoder(r.Body).Decode(&item)
+		if err != nil || len(item.Data) == 0 {
+			writeError(w, http.StatusBadRequest, "Invalid item")
+			return
+		}
+
+		item, err = s.store.put(name, item.Revision, item.Data)
+		if err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, RemoteItem{Name: item.Name, Revision: item.Revision})
+	case http.MethodDelete:
+		revision, err := strconv.ParseInt(r.URL.Query().Get("revision"), 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid revision")
+			return
+		}
+
+		err = s.store.delete(name, revision)
+		if err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+func writeStoreError(w http.ResponseWriter, err error) {
+	switch err {
+	case errNotFound:
+		writeError(w, http.StatusNotFound, err.Error())
+	case errConflict:
+		writeError(w, http.StatusConflict, err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,76 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestServer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "passline-server")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s, err := New(dir)
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+
+	token, err := AddDevice(dir, "laptop")
+	if err != nil {
+		t.Fatalf("AddDevice() error: %v", err)
+	}
+
+	request := func(method, path, token string, body interface{}) *httptest.ResponseRecorder {
+		data, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, path, bytes.NewReader(data))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := request(http.MethodGet, "/v1/items", "invalid", nil); rec.Code != http.StatusUnauthorized {
+		t.Errorf("GET with invalid token = %d; wanted %d", rec.Code, http.StatusUnauthorized)
+	}
+
+	item := RemoteItem{Revision: 0, Data: json.RawMessage(`{"name":"github.com"}`)}
+	rec := request(http.MethodPut, "/v1/items/github.com", token, item)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("PUT new item = %d; wanted %d", rec.Code, http.StatusOK)
+	}
+
+	created := RemoteItem{}
+	json.Unmarshal(rec.Body.Bytes(), &created)
+
+	// A write based on an outdated revision is rejected
+	if rec := request(http.MethodPut, "/v1/items/github.com", token, item); rec.Code != http.StatusConflict {
+		t.Errorf("PUT with outdated revision = %d; wanted %d", rec.Code, http.StatusConflict)
+	}
+
+	item.Revision = created.Revision
+	if rec := request(http.MethodPut, "/v1/items/github.com", token, item); rec.Code != http.StatusOK {
+		t.Errorf("PUT with current revision = %d; wanted %d", rec.Code, http.StatusOK)
+	}
+
+	list := ItemList{}
+	json.Unmarshal(request(http.MethodGet, "/v1/items", token, nil).Body.Bytes(), &list)
+	if len(list.Items) != 1 || list.Revision != 2 {
+		t.Errorf("GET /v1/items = %+v; wanted one item at revision 2", list)
+	}
+
+	err = RemoveDevice(dir, "laptop")
+	if err != nil {
+		t.Fatalf("RemoveDevice() error: %v", err)
+	}
+
+	if rec := request(http.MethodGet, "/v1/items", token, nil); rec.Code != http.StatusUnauthorized {
+		t.Errorf("GET with revoked token = %d; wanted %d", rec.Code, http.StatusUnauthorized)
+	}
+}
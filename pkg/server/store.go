@@ -0,0 +1,166 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+var (
+	errNotFound = errors.New("Item not found")
+	errConflict = errors.New("Revision conflict")
+)
+
+// store keeps the opaque item blobs in a json file. Every write increments
+// the global revision, which becomes the revision of the written item.
+type store struct {
+	mu    sync.Mutex
+	file  string
+	state storeState
+}
+
+type storeState struct {
+	Revision int64                 `json:"revision"`
+	Items    map[string]RemoteItem `json:"items"`
+	// Deleted maps names of deleted items to the revision of the deletion
+	Deleted map[string]int64 `json:"deleted"`
+}
+
+func openStore(file string) (*store, error) {
+	s := &store{file: file, state: storeState{Items: map[string]RemoteItem{}, Deleted: map[string]int64{}}}
+
+	data, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, &s.state)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.state.Items == nil {
+		s.state.Items = map[string]RemoteItem{}
+	}
+	if s.state.Deleted == nil {
+		s.state.Deleted = map[string]int64{}
+	}
+
+	return s, nil
+}
+
+func (s *store) list() ([]RemoteItem, int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	items := make([]RemoteItem, 0, len(s.state.Items))
+	for _, item := range s.state.Items {
+		items = append(items, item)
+	}
+
+	sort.Slice(items, func(i, j int) bool { return items[i].Name < items[j].Name })
+	return items, s.state.Revision
+}
+
+func (s *store) get(name string) (RemoteItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, ok := s.state.Items[name]
+	if !ok {
+		return RemoteItem{}, errNotFound
+	}
+
+	return item, nil
+}
+
+// put writes the item if its current revision equals revision, 0 means the
+// item must not exist
+func (s *store) put(name string, revision int64, data json.RawMessage) (RemoteItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.state.Items[name].Revision != revision {
+		return RemoteItem{}, errConflict
+	}
+
+	s.state.Revision++
+	item := RemoteItem{Name: name, Revision: s.state.Revision, Data: data}
+	s.state.Items[name] = item
+	delete(s.state.Deleted, name)
+
+	return item, s.save()
+}
+
+func (s *store) delete(name string, revision int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, ok := s.state.Items[name]
+	if !ok {
+		return errNotFound
+	}
+
+	if item.Revision != revision {
+		return errConflict
+	}
+
+	s.state.Revision++
+	delete(s.state.Items, name)
+	s.state.Deleted[name] = s.state.Revision
+
+	return s.save()
+}
+
+// replace removes all items and writes the given ones
+func (s *store) replace(items []RemoteItem) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.state.Revision++
+	for name := range s.state.Items {
+		s.state.Deleted[name] = s.state.Revision
+	}
+
+	s.state.Items = map[string]RemoteItem{}
+	for _, item := range items {
+		item.Revision = s.state.Revision
+		s.state.Items[item.Name] = item
+		delete(s.state.Deleted, item.Name)
+	}
+
+	return s.state.Revision, s.save()
+}
+
+func (s *store) save() error {
+	data, err := json.Marshal(s.state)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(s.file), ".store")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), s.file)
+}
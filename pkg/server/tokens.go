@@ -0,0 +1,119 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const tokensFile = "tokens.json"
+
+// Device is a client allowed to access the server
+type Device struct {
+	Name string `json:"name"`
+	// Hash is the sha256 of the token, the token itself is never stored
+	Hash    string    `json:"hash"`
+	Created time.Time `json:"created"`
+}
+
+// AddDevice creates a new token for the device and returns it
+func AddDevice(dir, name string) (string, error) {
+	devices, err := ListDevices(dir)
+	if err != nil {
+		return "", err
+	}
+
+	for _, device := range devices {
+		if device.Name == name {
+			return "", errors.New("Device already exists: " + name)
+		}
+	}
+
+	raw := make([]byte, 32)
+	_, err = io.ReadFull(rand.Reader, raw)
+	if err != nil {
+		return "", err
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(raw)
+	devices = append(devices, Device{Name: name, Hash: hashToken(token), Created: time.Now()})
+
+	return token, saveDevices(dir, devices)
+}
+
+// RemoveDevice revokes the token of the device
+func RemoveDevice(dir, name string) error {
+	devices, err := ListDevices(dir)
+	if err != nil {
+		return err
+	}
+
+	for i, device := range devices {
+		if device.Name == name {
+			return saveDevices(dir, append(devices[:i], devices[i+1:]...))
+		}
+	}
+
+	return errors.New("Device not found: " + name)
+}
+
+// ListDevices returns all devices with access to the server
+func ListDevices(dir string) ([]Device, error) {
+	devices := []Device{}
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, tokensFile))
+	if os.IsNotExist(err) {
+		return devices, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, &devices)
+	return devices, err
+}
+
+func saveDevices(dir string, devices []Device) error {
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(devices, "", " ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filepath.Join(dir, tokensFile), data, 0600)
+}
+
+// authenticate returns the device of the token
+func authenticate(dir, token string) (Device, bool) {
+	devices, err := ListDevices(dir)
+	if err != nil || token == "" {
+		return Device{}, false
+	}
+
+	hash := hashToken(token)
+	for _, device := range devices {
+		if subtle.ConstantTimeCompare([]byte(device.Hash), []byte(hash)) == 1 {
+			return device, true
+		}
+	}
+
+	return Device{}, false
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,181 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+)
+
+const blobRetries = 3
+
+var (
+	errBlobNotFound       = errors.New("Not found")
+	errPreconditionFailed = errors.New("Storage was changed by another device, please try again")
+)
+
+// blobStore reads and writes the whole vault as a single object. Every
+// version of the object is identified by an etag.
+type blobStore interface {
+	// get returns errBlobNotFound if the object does not exist
+	get(ctx context.Context) ([]byte, string, error)
+	// put only writes if the object still has the given etag, or does not
+	// exist for an empty etag, otherwise it returns errPreconditionFailed
+	put(ctx context.Context, data []byte, etag string) error
+}
+
+// blobStorage implements Storage on top of a blobStore. Changes are applied
+// to the latest version and retried if another device wrote in between.
+type blobStorage struct {
+	store blobStore
+}
+
+func (b *blobStorage) GetItemByName(ctx context.Context, name string) (Item, error) {
+	data, _, err := b.read(ctx)
+	if err != nil {
+		return Item{}, err
+	}
+
+	index := getIndexOfItem(data.Items, Item{Name: name})
+	if index == -1 {
+		return Item{}, errors.New("Item not found")
+	}
+
+	return data.Items[index], nil
+}
+
+func (b *blobStorage) GetItemByIndex(ctx context.Context, index int) (Item, error) {
+	items, err := b.GetAllItems(ctx)
+	if err != nil {
+		return Item{}, err
+	}
+
+	if index < 0 || index >= len(items) {
+		return Item{}, errors.New("Out of index")
+	}
+
+	return items[index], nil
+}
+
+func (b *blobStorage) GetAllItems(ctx context.Context) ([]Item, error) {
+	data, _, err := b.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Sort(ByName(data.Items))
+	return data.Items, nil
+}
+
+func (b *blobStorage) CreateItem(ctx context.Context, item Item) error {
+	return b.update(ctx, func(data *Data) error {
+		if getIndexOfItem(data.Items, item) != -1 {
+			return errors.New("Item already exists")
+		}
+
+		data.Items = append(data.Items, item)
+		return nil
+	})
+}
+
+func (b *blobStorage) AddCredential(ctx context.Context, name string, credential Credential) error {
+	return b.update(ctx, func(data *Data) error {
+		index := getIndexOfItem(data.Items, Item{Name: name})
+		if index == -1 {
+			return errors.New("Item not found")
+		}
+
+		if getIndexOfCredential(data.Items[index].Credentials, credential) != -1 {
+			return errors.New("Username already exists")
+		}
+
+		data.Items[index].Credentials = append(data.Items[index].Credentials, credential)
+		return nil
+	})
+}
+
+func (b *blobStorage) DeleteCredential(ctx context.Context, item Item, credential Credential) error {
+	return b.update(ctx, func(data *Data) error {
+		index := getIndexOfItem(data.Items, item)
+		if index == -1 {
+			return errors.New("Item not found")
+		}
+
+		indexCredential := getIndexOfCredential(data.Items[index].Credentials, credential)
+		if indexCredential == -1 {
+			return errors.New("Item not found")
+		}
+
+		if len(data.Items[index].Credentials) > 1 {
+			data.Items[index].Credentials = removeFromCredentials(data.Items[index].Credentials, indexCredential)
+		} else {
+			data.Items = removeFromItems(data.Items, index)
+		}
+
+		return nil
+	})
+}
+
+func (b *blobStorage) UpdateItem(ctx context.Context, item Item) error {
+	return b.update(ctx, func(data *Data) error {
+		index := getIndexOfItem(data.Items, item)
+		if index == -1 {
+			return errors.New("Item not found")
+		}
+
+		data.Items[index] = item
+		return nil
+	})
+}
+
+func (b *blobStorage) SetData(ctx context.Context, data Data) error {
+	return b.update(ctx, func(current *Data) error {
+		*current = data
+		return nil
+	})
+}
+
+func (b *blobStorage) read(ctx context.Context) (Data, string, error) {
+	data := Data{Items: []Item{}}
+
+	body, etag, err := b.store.get(ctx)
+	if err == errBlobNotFound {
+		return data, "", nil
+	}
+	if err != nil {
+		return data, "", err
+	}
+
+	err = json.Unmarshal(body, &data)
+	if err != nil {
+		return data, "", err
+	}
+
+	return data, etag, nil
+}
+
+func (b *blobStorage) update(ctx context.Context, fn func(*Data) error) error {
+	for i := 0; i < blobRetries; i++ {
+		data, etag, err := b.read(ctx)
+		if err != nil {
+			return err
+		}
+
+		err = fn(&data)
+		if err != nil {
+			return err
+		}
+
+		body, err := json.MarshalIndent(data, "", " ")
+		if err != nil {
+			return err
+		}
+
+		err = b.store.put(ctx, body, etag)
+		if err != errPreconditionFailed {
+			return err
+		}
+	}
+
+	return errPreconditionFailed
+}
@@ -0,0 +1,501 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"path"
+	"sort"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/perryrh0dan/passline/pkg/config"
+)
+
+// Cache keeps a local replica of a remote storage. The remote is read once
+// per run, afterwards all reads are answered from the replica. Changes are
+// queued and written to the remote as soon as it is reachable. Every item
+// has a revision counter that is incremented whenever a new version of the
+// item is seen on the remote. A queued change is only applied if the remote
+// item is still at the revision the change is based on, otherwise it is
+// reported as conflict.
+type Cache struct {
+	remote  Storage
+	file    string
+	state   cacheState
+	synced  bool
+	offline bool
+}
+
+type cacheState struct {
+	Items     []cachedItem `json:"items"`
+	Pending   []change     `json:"pending"`
+	Conflicts []Conflict   `json:"conflicts"`
+}
+
+type cachedItem struct {
+	Item     Item   `json:"item"`
+	Revision int    `json:"revision"`
+	Hash     string `json:"hash"`
+}
+
+// change is a queued local change, Item is nil if the item was deleted
+type change struct {
+	Name     string `json:"name"`
+	Item     *Item  `json:"item"`
+	Revision int    `json:"revision"`
+	Hash     string `json:"hash"`
+}
+
+// Conflict is a local change that was not applied because the item was
+// changed on the remote as well. Local or Remote are nil for deleted items.
+type Conflict struct {
+	Name           string    `json:"name"`
+	Local          *Item     `json:"local"`
+	LocalRevision  int       `json:"localRevision"`
+	Remote         *Item     `json:"remote"`
+	RemoteRevision int       `json:"remoteRevision"`
+	Date           time.Time `json:"date"`
+}
+
+// NewCache returns the cache of the remote storage. Every vault and firestore
+// namespace has its own replica.
+func NewCache(remote Storage, name string) (*Cache, error) {
+	cfg, err := config.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	storageDir := path.Join(cfg.Directory, "storage")
+	ensureDirectories(storageDir, "")
+
+	file := "cache-" + name + "-" + cfg.Vault
+	if name == "firestore" && cfg.Firestore.Vault != "" {
+		file += "-" + cfg.Firestore.Vault
+	}
+
+	return newCache(remote, path.Join(storageDir, file+".json"))
+}
+
+func newCache(remote Storage, file string) (*Cache, error) {
+	c := &Cache{remote: remote, file: file}
+
+	data, err := ioutil.ReadFile(file)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	if err == nil {
+		err = json.Unmarshal(data, &c.state)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+func (c *Cache) GetItemByName(ctx context.Context, name string) (Item, error) {
+	err := c.ensureSynced(ctx)
+	if err != nil {
+		return Item{}, err
+	}
+
+	index := c.indexOf(name)
+	if index == -1 {
+		return Item{}, errors.New("Item not found")
+	}
+
+	return c.state.Items[index].Item, nil
+}
+
+func (c *Cache) GetItemByIndex(ctx context.Context, index int) (Item, error) {
+	items, err := c.GetAllItems(ctx)
+	if err != nil {
+		return Item{}, err
+	}
+
+	if index < 0 || index >= len(items) {
+		return Item{}, errors.New("Out of index")
+	}
+
+	return items[index], nil
+}
+
+func (c *Cache) GetAllItems(ctx context.Context) ([]Item, error) {
+	err := c.ensureSynced(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	items := []Item{}
+	for _, cached := range c.state.Items {
+		items = append(items, cached.Item)
+	}
+
+	sort.Sort(ByName(items))
+	return items, nil
+}
+
+func (c *Cache) CreateItem(ctx context.Context, item Item) error {
+	err := c.ensureSynced(ctx)
+	if err != nil {
+		return err
+	}
+
+	if c.indexOf(item.Name) != -1 {
+		return errors.New("Item already exists")
+	}
+
+	return c.change(ctx, item.Name, &item)
+}
+
+func (c *Cache) AddCredential(ctx context.Context, name string, credential Credential) error {
+	item, err := c.GetItemByName(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	if getIndexOfCredential(item.Credentials, credential) != -1 {
+		return errors.New("Username already exists")
+	}
+
+	item.Credentials = append(append([]Credential{}, item.Credentials...), credential)
+	return c.change(ctx, name, &item)
+}
+
+func (c *Cache) DeleteCredential(ctx context.Context, item Item, credential Credential) error {
+	item, err := c.GetItemByName(ctx, item.Name)
+	if err != nil {
+		return err
+	}
+
+	index := getIndexOfCredential(item.Credentials, credential)
+	if index == -1 {
+		return errors.New("Item not found")
+	}
+
+	if len(item.Credentials) == 1 {
+		return c.change(ctx, item.Name, nil)
+	}
+
+	item.Credentials = removeFromCredentials(append([]Credential{}, item.Credentials...), index)
+	return c.change(ctx, item.Name, &item)
+}
+
+func (c *Cache) UpdateItem(ctx context.Context, item Item) error {
+	err := c.ensureSynced(ctx)
+	if err != nil {
+		return err
+	}
+
+	if c.indexOf(item.Name) == -1 {
+		return errors.New("Item not found")
+	}
+
+	return c.change(ctx, item.Name, &item)
+}
+
+func (c *Cache) SetData(ctx context.Context, data Data) error {
+	err := c.ensureSynced(ctx)
+	if err != nil {
+		return err
+	}
+
+	names := map[string]bool{}
+	for _, cached := range c.state.Items {
+		names[cached.Item.Name] = true
+	}
+
+	for i := range data.Items {
+		c.queue(data.Items[i].Name, &data.Items[i])
+		delete(names, data.Items[i].Name)
+	}
+
+	for name := range names {
+		c.queue(name, nil)
+	}
+
+	return c.flush(ctx)
+}
+
+// Sync writes the queued changes and refreshes the replica
+func (c *Cache) Sync(ctx context.Context) error {
+	c.offline = false
+	err := c.sync(ctx)
+	if err != nil {
+		return err
+	}
+
+	if syncer, ok := c.remote.(Syncer); ok {
+		return syncer.Sync(ctx)
+	}
+
+	return nil
+}
+
+// Conflicts returns the conflicts of all syncs since they were last resolved
+func (c *Cache) Conflicts() []Conflict {
+	return c.state.Conflicts
+}
+
+// ResolveConflicts either writes the local versions of all conflicting items
+// or discards them
+func (c *Cache) ResolveConflicts(ctx context.Context, keepLocal bool) error {
+	conflicts := c.state.Conflicts
+	c.state.Conflicts = nil
+
+	if keepLocal {
+		for _, conflict := range conflicts {
+			c.queue(conflict.Name, conflict.Local)
+		}
+	}
+
+	return c.flush(ctx)
+}
+
+// change applies a local change to the replica and queues it for the remote
+func (c *Cache) change(ctx context.Context, name string, item *Item) error {
+	c.queue(name, item)
+	return c.flush(ctx)
+}
+
+func (c *Cache) queue(name string, item *Item) {
+	index := c.indexOf(name)
+
+	// Multiple changes of an item are based on the first one
+	pending := -1
+	for i, ch := range c.state.Pending {
+		if ch.Name == name {
+			pending = i
+		}
+	}
+
+	if pending != -1 {
+		c.state.Pending[pending].Item = item
+	} else {
+		ch := change{Name: name, Item: item}
+		if index != -1 {
+			ch.Revision = c.state.Items[index].Revision
+			ch.Hash = c.state.Items[index].Hash
+		}
+		c.state.Pending = append(c.state.Pending, ch)
+	}
+
+	switch {
+	case item == nil && index != -1:
+		c.state.Items = append(c.state.Items[:index], c.state.Items[index+1:]...)
+	case item != nil && index != -1:
+		c.state.Items[index].Item = *item
+	case item != nil:
+		c.state.Items = append(c.state.Items, cachedItem{Item: *item})
+	}
+}
+
+// flush saves the replica and writes the queue if the remote is reachable.
+// Being offline is no error, the changes stay queued.
+func (c *Cache) flush(ctx context.Context) error {
+	err := c.save()
+	if err != nil {
+		return err
+	}
+
+	if c.offline {
+		return nil
+	}
+
+	return c.online(c.sync(ctx))
+}
+
+func (c *Cache) ensureSynced(ctx context.Context) error {
+	if c.synced || c.offline {
+		return nil
+	}
+
+	return c.online(c.sync(ctx))
+}
+
+// online drops the error of a sync if the remote was not reachable
+func (c *Cache) online(err error) error {
+	if c.offline {
+		return nil
+	}
+
+	return err
+}
+
+// sync writes the queued changes and reads the remote items. If the remote
+// is not reachable the cache is offline until the next Sync.
+func (c *Cache) sync(ctx context.Context) error {
+	items, err := c.remote.GetAllItems(ctx)
+	if err != nil {
+		c.offline = isOffline(err)
+		return err
+	}
+
+	remote := map[string]Item{}
+	for _, item := range items {
+		remote[item.Name] = item
+	}
+
+	var pushErr error
+	pending := []change{}
+	for i, ch := range c.state.Pending {
+		current, exists := remote[ch.Name]
+		currentHash := ""
+		if exists {
+			currentHash = hashItem(current)
+		}
+
+		if currentHash != ch.Hash {
+			conflict := Conflict{Name: ch.Name, Local: ch.Item, LocalRevision: ch.Revision + 1, RemoteRevision: ch.Revision + 1, Date: time.Now()}
+			if exists {
+				conflict.Remote = &current
+			}
+			c.state.Conflicts = append(c.state.Conflicts, conflict)
+			continue
+		}
+
+		// Changes that fail stay queued
+		pushErr = c.push(ctx, ch, current, exists)
+		if pushErr != nil {
+			c.offline = isOffline(pushErr)
+			pending = append(pending, c.state.Pending[i:]...)
+			break
+		}
+
+		if ch.Item != nil {
+			remote[ch.Name] = *ch.Item
+		} else {
+			delete(remote, ch.Name)
+		}
+	}
+	c.state.Pending = pending
+
+	c.refresh(remote)
+	c.synced = true
+
+	err = c.save()
+	if err != nil {
+		return err
+	}
+
+	return pushErr
+}
+
+// push writes a change to the remote
+func (c *Cache) push(ctx context.Context, ch change, current Item, exists bool) error {
+	switch {
+	case ch.Item == nil && exists:
+		for _, credential := range current.Credentials {
+			err := c.remote.DeleteCredential(ctx, current, credential)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case ch.Item == nil:
+		return nil
+	case exists:
+		return c.remote.UpdateItem(ctx, *ch.Item)
+	default:
+		return c.remote.CreateItem(ctx, *ch.Item)
+	}
+}
+
+// refresh replaces the replica with the remote items and queued changes and
+// increments the revision of every item that changed on the remote
+func (c *Cache) refresh(remote map[string]Item) {
+	previous := map[string]cachedItem{}
+	for _, cached := range c.state.Items {
+		previous[cached.Item.Name] = cached
+	}
+
+	for _, ch := range c.state.Pending {
+		delete(remote, ch.Name)
+		if ch.Item != nil {
+			remote[ch.Name] = *ch.Item
+		}
+	}
+
+	c.state.Items = []cachedItem{}
+	for name, item := range remote {
+		cached := cachedItem{Item: item, Revision: previous[name].Revision, Hash: hashItem(item)}
+		if cached.Hash != previous[name].Hash {
+			cached.Revision++
+		}
+
+		// Queued changes keep the revision they are based on
+		for _, ch := range c.state.Pending {
+			if ch.Name == name {
+				cached.Revision = ch.Revision
+				cached.Hash = ch.Hash
+			}
+		}
+
+		c.state.Items = append(c.state.Items, cached)
+	}
+
+	// Conflicting items show the remote revision
+	for i, conflict := range c.state.Conflicts {
+		if cached, ok := remote[conflict.Name]; ok && conflict.Remote != nil && hashItem(cached) == hashItem(*conflict.Remote) {
+			c.state.Conflicts[i].RemoteRevision = c.state.Items[c.indexOf(conflict.Name)].Revision
+		}
+	}
+}
+
+// isOffline reports if err means that the remote is not reachable
+func isOffline(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return true
+	}
+
+	return false
+}
+
+func (c *Cache) indexOf(name string) int {
+	for i, cached := range c.state.Items {
+		if cached.Item.Name == name {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func (c *Cache) save() error {
+	data, err := json.Marshal(c.state)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(c.file, data, 0600)
+}
+
+// hashItem identifies a version of an item independent of nil or empty lists
+func hashItem(item Item) string {
+	normalized := Item{Name: item.Name, Folder: item.Folder, Tags: item.Tags, URIs: item.URIs, Credentials: []Credential{}}
+	for _, credential := range item.Credentials {
+		if credential.RecoveryCodes == nil {
+			credential.RecoveryCodes = []string{}
+		}
+		normalized.Credentials = append(normalized.Credentials, credential)
+	}
+
+	data, _ := json.Marshal(normalized)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
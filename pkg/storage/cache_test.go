@@ -0,0 +1,150 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// unreachable makes a storage fail like a remote without network or one
+// that rejects the requests
+type unreachable struct {
+	Storage
+	offline  bool
+	rejected error
+}
+
+func (u *unreachable) err() error {
+	if u.offline {
+		return &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("network is unreachable")}
+	}
+	return u.rejected
+}
+
+func (u *unreachable) GetAllItems(ctx context.Context) ([]Item, error) {
+	if err := u.err(); err != nil {
+		return nil, err
+	}
+	return u.Storage.GetAllItems(ctx)
+}
+
+func (u *unreachable) CreateItem(ctx context.Context, item Item) error {
+	if err := u.err(); err != nil {
+		return err
+	}
+	return u.Storage.CreateItem(ctx, item)
+}
+
+func (u *unreachable) UpdateItem(ctx context.Context, item Item) error {
+	if err := u.err(); err != nil {
+		return err
+	}
+	return u.Storage.UpdateItem(ctx, item)
+}
+
+func (u *unreachable) DeleteCredential(ctx context.Context, item Item, credential Credential) error {
+	if err := u.err(); err != nil {
+		return err
+	}
+	return u.Storage.DeleteCredential(ctx, item, credential)
+}
+
+func TestCache(t *testing.T) {
+	ctx := context.Background()
+
+	dir, err := ioutil.TempDir("", "passline-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := newSQLite(filepath.Join(dir, "storage.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote := &unreachable{Storage: db}
+
+	perry := Credential{Username: "perry", Password: "encrypted", RecoveryCodes: []string{}}
+	other := Credential{Username: "other", Password: "encrypted", RecoveryCodes: []string{}}
+	db.CreateItem(ctx, Item{Name: "github.com", Credentials: []Credential{perry}})
+	db.CreateItem(ctx, Item{Name: "gitlab.com", Credentials: []Credential{perry}})
+
+	c, err := newCache(remote, filepath.Join(dir, "cache.json"))
+	if err != nil {
+		t.Fatalf("newCache() error: %v", err)
+	}
+
+	items, err := c.GetAllItems(ctx)
+	if err != nil || len(items) != 2 {
+		t.Fatalf("GetAllItems() = %d items, %v; wanted 2", len(items), err)
+	}
+
+	// Changes made offline are queued and read from the replica
+	remote.offline = true
+	c, _ = newCache(remote, filepath.Join(dir, "cache.json"))
+
+	if err = c.AddCredential(ctx, "github.com", other); err != nil {
+		t.Fatalf("AddCredential() offline error: %v", err)
+	}
+	if err = c.AddCredential(ctx, "gitlab.com", other); err != nil {
+		t.Fatalf("AddCredential() offline error: %v", err)
+	}
+
+	item, _ := c.GetItemByName(ctx, "github.com")
+	if len(item.Credentials) != 2 {
+		t.Errorf("GetItemByName() offline returned %d credentials; wanted 2", len(item.Credentials))
+	}
+
+	// Meanwhile gitlab.com is changed by another device
+	db.DeleteCredential(ctx, Item{Name: "gitlab.com"}, perry)
+	db.CreateItem(ctx, Item{Name: "gitlab.com", Credentials: []Credential{other}})
+
+	remote.offline = false
+	c, _ = newCache(remote, filepath.Join(dir, "cache.json"))
+	if err = c.Sync(ctx); err != nil {
+		t.Fatalf("Sync() error: %v", err)
+	}
+
+	item, _ = db.GetItemByName(ctx, "github.com")
+	if len(item.Credentials) != 2 {
+		t.Errorf("remote github.com has %d credentials after sync; wanted 2", len(item.Credentials))
+	}
+
+	conflicts := c.Conflicts()
+	if len(conflicts) != 1 || conflicts[0].Name != "gitlab.com" || conflicts[0].RemoteRevision != 2 {
+		t.Fatalf("Conflicts() = %+v; wanted gitlab.com at remote revision 2", conflicts)
+	}
+
+	item, _ = c.GetItemByName(ctx, "gitlab.com")
+	if len(item.Credentials) != 1 || item.Credentials[0].Username != "other" {
+		t.Errorf("GetItemByName() of conflicting item = %+v; wanted remote version", item)
+	}
+
+	if err = c.ResolveConflicts(ctx, true); err != nil {
+		t.Fatalf("ResolveConflicts() error: %v", err)
+	}
+
+	item, _ = db.GetItemByName(ctx, "gitlab.com")
+	if len(item.Credentials) != 2 || len(c.Conflicts()) != 0 {
+		t.Errorf("remote gitlab.com = %+v after keeping local version", item)
+	}
+	// Other errors of the remote are no reason to go offline
+	remote.rejected = errors.New("Permission denied")
+	if err = c.UpdateItem(ctx, Item{Name: "github.com", Credentials: []Credential{perry}}); err != remote.rejected {
+		t.Errorf("UpdateItem() rejected by the remote = %v; wanted %v", err, remote.rejected)
+	}
+
+	remote.rejected = nil
+	if err = c.Sync(ctx); err != nil {
+		t.Fatalf("Sync() error: %v", err)
+	}
+
+	item, _ = db.GetItemByName(ctx, "github.com")
+	if len(item.Credentials) != 1 {
+		t.Errorf("remote github.com has %d credentials after the rejected change was synced; wanted 1", len(item.Credentials))
+	}
+}
@@ -2,57 +2,153 @@ package storage
 
 import (
 	"errors"
-	"log"
 	"os"
-	"path"
 	"sort"
+	"time"
 
 	"golang.org/x/net/context"
 
 	"cloud.google.com/go/firestore"
-	firebase "firebase.google.com/go"
 	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/perryrh0dan/passline/pkg/config"
 )
 
+// emulatorProject is used with the emulator if no project is configured
+const emulatorProject = "passline"
+
+// vaultsCollection contains a document per vault with the items as subcollection
+const vaultsCollection = "vaults"
+
 type FireStore struct {
-	client *firestore.Client
+	client     *firestore.Client
+	collection string
+	vault      string
 }
 
-func NewFirestore(ctx context.Context) (*FireStore, error) {
-	mainDir, _ := getMainDir()
-	credentialsFile := path.Join(mainDir, "firestore.json")
+// FirestoreVault is the document of a vault
+type FirestoreVault struct {
+	Name    string
+	Created time.Time
+}
+
+func init() {
+	Register(Backend{
+		Name:        "firestore",
+		Description: "Google Cloud Firestore collection",
+		Settings:    []string{"Firestore.Project", "Firestore.Collection", "Firestore.Vault", "Firestore.CredentialsFile"},
+		Remote:      true,
+		New: func(ctx context.Context) (Storage, error) {
+			return NewFirestore(ctx)
+		},
+	})
+}
 
-	// Check for credentials file
-	_, err := os.Stat(credentialsFile)
+func NewFirestore(ctx context.Context) (*FireStore, error) {
+	cfg, err := config.Get()
 	if err != nil {
-		log.Fatalf("error missing firebase credentials file\n")
 		return nil, err
 	}
 
-	opt := option.WithCredentialsFile(credentialsFile)
-	app, err := firebase.NewApp(ctx, nil, opt)
-	if err != nil {
-		log.Fatalf("error initializing app: %v\n", err)
-		return nil, err
+	return newFirestore(ctx, cfg.Firestore)
+}
+
+// newFirestore connects to the emulator if FIRESTORE_EMULATOR_HOST is set,
+// otherwise it authenticates with the credentials file
+func newFirestore(ctx context.Context, cfg config.Firestore) (*FireStore, error) {
+	project := cfg.Project
+	opts := []option.ClientOption{}
+
+	if os.Getenv("FIRESTORE_EMULATOR_HOST") != "" {
+		if project == "" {
+			project = emulatorProject
+		}
+	} else {
+		_, err := os.Stat(cfg.CredentialsFile)
+		if err != nil {
+			return nil, errors.New("Missing firestore credentials file " + cfg.CredentialsFile)
+		}
+
+		if project == "" {
+			project = firestore.DetectProjectID
+		}
+		opts = append(opts, option.WithCredentialsFile(cfg.CredentialsFile))
 	}
 
-	client, err := app.Firestore(ctx)
+	client, err := firestore.NewClient(ctx, project, opts...)
 	if err != nil {
-		log.Fatalln(err)
 		return nil, err
 	}
 
-	return &FireStore{client: client}, nil
+	return &FireStore{client: client, collection: cfg.Collection, vault: cfg.Vault}, nil
+}
+
+func (fs *FireStore) items() *firestore.CollectionRef {
+	if fs.vault == "" {
+		return fs.client.Collection(fs.collection)
+	}
+
+	return fs.client.Collection(vaultsCollection).Doc(fs.vault).Collection("items")
+}
+
+// ensureVault creates the document of the vault, subcollections of missing
+// documents are not listed by Vaults
+func (fs *FireStore) ensureVault(ctx context.Context) error {
+	if fs.vault == "" {
+		return nil
+	}
+
+	vault := FirestoreVault{Name: fs.vault, Created: time.Now()}
+	_, err := fs.client.Collection(vaultsCollection).Doc(fs.vault).Create(ctx, vault)
+	if status.Code(err) == codes.AlreadyExists {
+		return nil
+	}
+
+	return err
+}
+
+// Vaults returns all vaults of the project
+func (fs *FireStore) Vaults(ctx context.Context) ([]FirestoreVault, error) {
+	vaults := []FirestoreVault{}
+	iter := fs.client.Collection(vaultsCollection).Documents(ctx)
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var vault FirestoreVault
+		err = doc.DataTo(&vault)
+		if err != nil {
+			return nil, err
+		}
+		vaults = append(vaults, vault)
+	}
+
+	sort.Slice(vaults, func(i, j int) bool { return vaults[i].Name < vaults[j].Name })
+	return vaults, nil
 }
 
 func (fs *FireStore) GetItemByName(ctx context.Context, name string) (Item, error) {
-	dsnap, err := fs.client.Collection("passline").Doc(name).Get(context.Background())
+	dsnap, err := fs.items().Doc(name).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return Item{}, errors.New("Item not found")
+	}
 	if err != nil {
 		return Item{}, err
 	}
+
 	var item Item
-	dsnap.DataTo(&item)
+	err = dsnap.DataTo(&item)
+	if err != nil {
+		return Item{}, err
+	}
 
 	return item, nil
 }
@@ -63,7 +159,7 @@ func (fs *FireStore) GetItemByIndex(ctx context.Context, index int) (Item, error
 		return Item{}, err
 	}
 
-	if index < 0 && index >= len(items) {
+	if index < 0 || index >= len(items) {
 		return Item{}, errors.New("Out of index")
 	}
 
@@ -72,7 +168,7 @@ func (fs *FireStore) GetItemByIndex(ctx context.Context, index int) (Item, error
 
 func (fs *FireStore) GetAllItems(ctx context.Context) ([]Item, error) {
 	items := []Item{}
-	iter := fs.client.Collection("passline").Documents(ctx)
+	iter := fs.items().Documents(ctx)
 	for {
 		doc, err := iter.Next()
 		if err == iterator.Done {
@@ -83,7 +179,10 @@ func (fs *FireStore) GetAllItems(ctx context.Context) ([]Item, error) {
 		}
 
 		var item Item
-		doc.DataTo(&item)
+		err = doc.DataTo(&item)
+		if err != nil {
+			return nil, err
+		}
 		items = append(items, item)
 	}
 
@@ -92,82 +191,104 @@ func (fs *FireStore) GetAllItems(ctx context.Context) ([]Item, error) {
 }
 
 func (fs *FireStore) CreateItem(ctx context.Context, item Item) error {
-	_, err := fs.client.Collection("passline").Doc(item.Name).Set(ctx, item)
-	if err != nil {
-		log.Fatalf("Failed adding item: %v", err)
-	}
-
-	return nil
-}
-
-func (fs *FireStore) AddCredential(ctx context.Context, name string, credential Credential) error {
-	item, err := fs.GetItemByName(ctx, name)
+	err := fs.ensureVault(ctx)
 	if err != nil {
 		return err
 	}
 
-	item.Credentials = append(item.Credentials, credential)
-	err = fs.CreateItem(ctx, item)
-	if err != nil {
-		log.Fatalf("Failed updating credentials: %v", err)
+	_, err = fs.items().Doc(item.Name).Create(ctx, item)
+	if status.Code(err) == codes.AlreadyExists {
+		return errors.New("Item already exists")
 	}
 
-	return nil
+	return err
 }
 
-func (fs *FireStore) deleteItem(ctx context.Context, item Item) error {
-	_, err := fs.client.Collection("passline").Doc(item.Name).Delete(ctx)
-	if err != nil {
-		log.Printf("An error has occured: %s", err)
-		return err
-	}
+func (fs *FireStore) AddCredential(ctx context.Context, name string, credential Credential) error {
+	return fs.modify(ctx, name, func(item *Item) error {
+		if getIndexOfCredential(item.Credentials, credential) != -1 {
+			return errors.New("Username already exists")
+		}
 
-	return nil
+		item.Credentials = append(item.Credentials, credential)
+		return nil
+	})
 }
 
 func (fs *FireStore) DeleteCredential(ctx context.Context, item Item, credential Credential) error {
-	indexCredential := getIndexOfCredential(item.Credentials, credential)
-	if indexCredential == -1 {
-		return errors.New("Item not found")
-	}
+	return fs.modify(ctx, item.Name, func(item *Item) error {
+		index := getIndexOfCredential(item.Credentials, credential)
+		if index == -1 {
+			return errors.New("Item not found")
+		}
+
+		item.Credentials = removeFromCredentials(item.Credentials, index)
+		return nil
+	})
+}
+
+func (fs *FireStore) UpdateItem(ctx context.Context, item Item) error {
+	return fs.modify(ctx, item.Name, func(current *Item) error {
+		*current = item
+		return nil
+	})
+}
+
+// modify changes an item in a transaction, an item without credentials is
+// deleted
+func (fs *FireStore) modify(ctx context.Context, name string, fn func(*Item) error) error {
+	ref := fs.items().Doc(name)
 
-	if len(item.Credentials) > 1 {
-		item.Credentials = removeFromCredentials(item.Credentials, indexCredential)
-		err := fs.CreateItem(ctx, item)
+	return fs.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		dsnap, err := tx.Get(ref)
+		if status.Code(err) == codes.NotFound {
+			return errors.New("Item not found")
+		}
 		if err != nil {
-			log.Fatalf("Failed updating credentials: %v", err)
+			return err
 		}
-	} else {
-		fs.deleteItem(ctx, item)
-	}
 
-	return nil
+		var item Item
+		err = dsnap.DataTo(&item)
+		if err != nil {
+			return err
+		}
+
+		err = fn(&item)
+		if err != nil {
+			return err
+		}
+
+		if len(item.Credentials) == 0 {
+			return tx.Delete(ref)
+		}
+
+		return tx.Set(ref, item)
+	})
 }
 
-func (fs *FireStore) UpdateItem(ctx context.Context, item Item) error {
-	err := fs.deleteItem(ctx, item)
+func (fs *FireStore) SetData(ctx context.Context, data Data) error {
+	err := fs.deleteCollection(ctx, 100)
 	if err != nil {
 		return err
 	}
 
-	err = fs.CreateItem(ctx, item)
+	if len(data.Items) == 0 {
+		return nil
+	}
+
+	err = fs.ensureVault(ctx)
 	if err != nil {
 		return err
 	}
 
-	return nil
-}
-
-func (fs *FireStore) SetData(ctx context.Context, data Data) error {
-	fs.deleteCollection(ctx, 100)
 	batch := fs.client.Batch()
-
 	for _, item := range data.Items {
-		itemRef := fs.client.Collection("passline").Doc(item.Name)
+		itemRef := fs.items().Doc(item.Name)
 		batch.Set(itemRef, item)
 	}
 
-	_, err := batch.Commit(ctx)
+	_, err = batch.Commit(ctx)
 	if err != nil {
 		return err
 	}
@@ -176,7 +297,7 @@ func (fs *FireStore) SetData(ctx context.Context, data Data) error {
 }
 
 func (fs *FireStore) deleteCollection(ctx context.Context, batchSize int) error {
-	ref := fs.client.Collection("passline")
+	ref := fs.items()
 
 	for {
 		// Get a batch of documents
@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/perryrh0dan/passline/pkg/config"
+)
+
+// TestFirestore runs against the emulator, start it with
+// gcloud beta emulators firestore start --host-port=localhost:8080
+// and set FIRESTORE_EMULATOR_HOST=localhost:8080
+func TestFirestore(t *testing.T) {
+	if os.Getenv("FIRESTORE_EMULATOR_HOST") == "" {
+		t.Skip("FIRESTORE_EMULATOR_HOST is not set")
+	}
+
+	ctx := context.Background()
+	collection := "passline-test-" + time.Now().Format("20060102150405")
+
+	fs, err := newFirestore(ctx, config.Firestore{Collection: collection})
+	if err != nil {
+		t.Fatalf("newFirestore() error: %v", err)
+	}
+	defer fs.SetData(ctx, Data{})
+
+	perry := Credential{Username: "perry", Password: "encrypted", RecoveryCodes: []string{}}
+	other := Credential{Username: "other", Password: "encrypted", RecoveryCodes: []string{}}
+
+	err = fs.CreateItem(ctx, Item{Name: "github.com", Credentials: []Credential{perry}})
+	if err != nil {
+		t.Fatalf("CreateItem() error: %v", err)
+	}
+
+	if err = fs.CreateItem(ctx, Item{Name: "github.com", Credentials: []Credential{perry}}); err == nil {
+		t.Errorf("CreateItem() of existing item succeeded")
+	}
+
+	if err = fs.AddCredential(ctx, "github.com", other); err != nil {
+		t.Fatalf("AddCredential() error: %v", err)
+	}
+
+	if err = fs.AddCredential(ctx, "github.com", other); err == nil {
+		t.Errorf("AddCredential() with existing username succeeded")
+	}
+
+	item, err := fs.GetItemByName(ctx, "github.com")
+	if err != nil || len(item.Credentials) != 2 {
+		t.Errorf("GetItemByName() = %+v, %v", item, err)
+	}
+
+	if err = fs.UpdateItem(ctx, Item{Name: "gitlab.com", Credentials: []Credential{perry}}); err == nil {
+		t.Errorf("UpdateItem() of missing item succeeded")
+	}
+
+	fs.DeleteCredential(ctx, item, perry)
+	fs.DeleteCredential(ctx, item, other)
+
+	items, err := fs.GetAllItems(ctx)
+	if err != nil || len(items) != 0 {
+		t.Errorf("GetAllItems() after deleting all credentials = %+v, %v", items, err)
+	}
+}
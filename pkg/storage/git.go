@@ -0,0 +1,349 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/perryrh0dan/passline/pkg/config"
+)
+
+const gitItemsDir = "items"
+
+// Git stores every item as an encrypted json file in a git repository and
+// commits on every change
+type Git struct {
+	dir      string
+	remote   string
+	branch   string
+	strategy string
+}
+
+func init() {
+	Register(Backend{
+		Name:        "git",
+		Description: "Git repository with one file per item",
+		Settings:    []string{"Git.Directory", "Git.Remote", "Git.Branch", "Git.Strategy"},
+		New: func(_ context.Context) (Storage, error) {
+			return NewGit()
+		},
+	})
+}
+
+func NewGit() (*Git, error) {
+	cfg, err := config.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	dir := cfg.Git.Directory
+	if dir == "" {
+		dir = path.Join(cfg.Directory, "git")
+	}
+
+	return newGit(dir, cfg.Git.Remote, cfg.Git.Branch, cfg.Git.Strategy)
+}
+
+func newGit(dir, remote, branch, strategy string) (*Git, error) {
+	if branch == "" {
+		branch = "master"
+	}
+
+	if strategy == "" {
+		strategy = "rebase"
+	}
+
+	g := &Git{dir: dir, remote: remote, branch: branch, strategy: strategy}
+	err := g.ensureRepository()
+	if err != nil {
+		return nil, err
+	}
+
+	return g, nil
+}
+
+func (g *Git) GetItemByName(ctx context.Context, name string) (Item, error) {
+	file, err := ioutil.ReadFile(g.itemFile(name))
+	if err != nil {
+		return Item{}, errors.New("Item not found")
+	}
+
+	item := Item{}
+	err = json.Unmarshal(file, &item)
+	if err != nil {
+		return Item{}, err
+	}
+
+	return item, nil
+}
+
+func (g *Git) GetItemByIndex(ctx context.Context, index int) (Item, error) {
+	items, err := g.GetAllItems(ctx)
+	if err != nil {
+		return Item{}, err
+	}
+
+	if index < 0 || index >= len(items) {
+		return Item{}, errors.New("Out of index")
+	}
+
+	return items[index], nil
+}
+
+func (g *Git) GetAllItems(ctx context.Context) ([]Item, error) {
+	files, err := ioutil.ReadDir(filepath.Join(g.dir, gitItemsDir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []Item{}, nil
+		}
+		return nil, err
+	}
+
+	items := []Item{}
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != ".json" {
+			continue
+		}
+
+		data, err := ioutil.ReadFile(filepath.Join(g.dir, gitItemsDir, file.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		item := Item{}
+		err = json.Unmarshal(data, &item)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	sort.Sort(ByName(items))
+	return items, nil
+}
+
+func (g *Git) CreateItem(ctx context.Context, item Item) error {
+	_, err := os.Stat(g.itemFile(item.Name))
+	if err == nil {
+		return errors.New("Item already exists")
+	}
+
+	err = g.writeItem(item)
+	if err != nil {
+		return err
+	}
+
+	return g.commit(fmt.Sprintf("Add item %s", item.Name))
+}
+
+func (g *Git) AddCredential(ctx context.Context, name string, credential Credential) error {
+	item, err := g.GetItemByName(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	if getIndexOfCredential(item.Credentials, credential) != -1 {
+		return errors.New("Username already exists")
+	}
+
+	item.Credentials = append(item.Credentials, credential)
+	err = g.writeItem(item)
+	if err != nil {
+		return err
+	}
+
+	return g.commit(fmt.Sprintf("Add %s to %s", credential.Username, name))
+}
+
+func (g *Git) DeleteCredential(ctx context.Context, item Item, credential Credential) error {
+	item, err := g.GetItemByName(ctx, item.Name)
+	if err != nil {
+		return err
+	}
+
+	index := getIndexOfCredential(item.Credentials, credential)
+	if index == -1 {
+		return errors.New("Item not found")
+	}
+
+	if len(item.Credentials) > 1 {
+		item.Credentials = removeFromCredentials(item.Credentials, index)
+		err = g.writeItem(item)
+	} else {
+		err = os.Remove(g.itemFile(item.Name))
+	}
+	if err != nil {
+		return err
+	}
+
+	return g.commit(fmt.Sprintf("Delete %s from %s", credential.Username, item.Name))
+}
+
+func (g *Git) UpdateItem(ctx context.Context, item Item) error {
+	err := g.writeItem(item)
+	if err != nil {
+		return err
+	}
+
+	return g.commit(fmt.Sprintf("Update item %s", item.Name))
+}
+
+func (g *Git) SetData(ctx context.Context, data Data) error {
+	err := os.RemoveAll(filepath.Join(g.dir, gitItemsDir))
+	if err != nil {
+		return err
+	}
+
+	for _, item := range data.Items {
+		err = g.writeItem(item)
+		if err != nil {
+			return err
+		}
+	}
+
+	return g.commit(fmt.Sprintf("Restore %d items", len(data.Items)))
+}
+
+// Sync pulls the changes of the remote with the configured strategy and
+// pushes the local commits
+func (g *Git) Sync(ctx context.Context) error {
+	if g.remote == "" {
+		return errors.New("No git remote configured")
+	}
+
+	_, err := g.git("fetch", "origin")
+	if err != nil {
+		return err
+	}
+
+	remoteBranch := "origin/" + g.branch
+	if _, err := g.git("rev-parse", "--verify", "--quiet", remoteBranch); err == nil {
+		err = g.integrate(remoteBranch)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Nothing to push on both sides
+	if _, err := g.git("rev-parse", "--verify", "--quiet", "HEAD"); err != nil {
+		return nil
+	}
+
+	_, err = g.git("push", "origin", "HEAD:refs/heads/"+g.branch)
+	return err
+}
+
+// integrate applies the commits of the remote branch to the local branch
+func (g *Git) integrate(remoteBranch string) error {
+	// Nothing committed yet, start from the remote
+	if _, err := g.git("rev-parse", "--verify", "--quiet", "HEAD"); err != nil {
+		_, err = g.git("reset", "--hard", remoteBranch)
+		return err
+	}
+
+	var err error
+	if g.strategy == "merge" {
+		_, err = g.git("merge", "--no-edit", remoteBranch)
+	} else {
+		_, err = g.git("rebase", remoteBranch)
+	}
+
+	if err != nil {
+		conflicts, _ := g.git("diff", "--name-only", "--diff-filter=U")
+		g.git(g.strategy, "--abort")
+		return fmt.Errorf("Conflicting changes in: %s", strings.Join(strings.Fields(conflicts), ", "))
+	}
+
+	return nil
+}
+
+func (g *Git) ensureRepository() error {
+	_, err := os.Stat(filepath.Join(g.dir, ".git"))
+	if os.IsNotExist(err) {
+		err = os.MkdirAll(g.dir, 0700)
+		if err != nil {
+			return err
+		}
+
+		_, err = g.git("init")
+		if err != nil {
+			return err
+		}
+
+		_, err = g.git("symbolic-ref", "HEAD", "refs/heads/"+g.branch)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Commits need an identity
+	if name, _ := g.git("config", "user.name"); name == "" {
+		g.git("config", "user.name", "Passline")
+	}
+	if email, _ := g.git("config", "user.email"); email == "" {
+		g.git("config", "user.email", "passline@localhost")
+	}
+
+	if g.remote != "" {
+		if url, _ := g.git("remote", "get-url", "origin"); url == "" {
+			_, err = g.git("remote", "add", "origin", g.remote)
+		} else if url != g.remote {
+			_, err = g.git("remote", "set-url", "origin", g.remote)
+		}
+	}
+
+	return err
+}
+
+func (g *Git) commit(message string) error {
+	_, err := g.git("add", "-A", gitItemsDir)
+	if err != nil {
+		return err
+	}
+
+	status, err := g.git("status", "--porcelain")
+	if err != nil || status == "" {
+		return err
+	}
+
+	_, err = g.git("commit", "-m", message)
+	return err
+}
+
+func (g *Git) git(args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", g.dir}, args...)...)
+	out, err := cmd.CombinedOutput()
+	output := strings.TrimSpace(string(out))
+	if err != nil {
+		return "", fmt.Errorf("git %s: %s", args[0], output)
+	}
+
+	return output, nil
+}
+
+func (g *Git) writeItem(item Item) error {
+	err := os.MkdirAll(filepath.Join(g.dir, gitItemsDir), 0700)
+	if err != nil {
+		return err
+	}
+
+	file, err := json.MarshalIndent(item, "", " ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(g.itemFile(item.Name), file, 0600)
+}
+
+func (g *Git) itemFile(name string) string {
+	return filepath.Join(g.dir, gitItemsDir, url.PathEscape(name)+".json")
+}
@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGitSync(t *testing.T) {
+	ctx := context.Background()
+
+	dir, err := ioutil.TempDir("", "passline-git")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	remote := filepath.Join(dir, "remote.git")
+	out, err := exec.Command("git", "init", "--bare", remote).CombinedOutput()
+	if err != nil {
+		t.Fatalf("git init --bare: %s", out)
+	}
+
+	a, err := newGit(filepath.Join(dir, "a"), remote, "master", "rebase")
+	if err != nil {
+		t.Fatalf("newGit() error: %v", err)
+	}
+
+	b, err := newGit(filepath.Join(dir, "b"), remote, "master", "rebase")
+	if err != nil {
+		t.Fatalf("newGit() error: %v", err)
+	}
+
+	credential := Credential{Username: "perry", Password: "encrypted", RecoveryCodes: []string{}}
+	err = a.CreateItem(ctx, Item{Name: "github.com", Credentials: []Credential{credential}})
+	if err != nil {
+		t.Fatalf("CreateItem() error: %v", err)
+	}
+
+	if log, _ := a.git("log", "--format=%s"); log != "Add item github.com" {
+		t.Errorf("CreateItem() commit message = %q", log)
+	}
+
+	if err = a.CreateItem(ctx, Item{Name: "github.com", Credentials: []Credential{}}); err == nil {
+		t.Errorf("CreateItem() of an existing item succeeded")
+	}
+
+	// Independent changes of both devices are combined
+	err = b.CreateItem(ctx, Item{Name: "gitlab.com", Credentials: []Credential{credential}})
+	if err != nil {
+		t.Fatalf("CreateItem() error: %v", err)
+	}
+
+	for _, g := range []*Git{a, b, a} {
+		err = g.Sync(ctx)
+		if err != nil {
+			t.Fatalf("Sync() error: %v", err)
+		}
+	}
+
+	for _, g := range []*Git{a, b} {
+		items, _ := g.GetAllItems(ctx)
+		if len(items) != 2 {
+			t.Errorf("GetAllItems() after sync returned %d items; wanted 2", len(items))
+		}
+	}
+
+	// Changes of the same item conflict
+	a.UpdateItem(ctx, Item{Name: "github.com", Credentials: []Credential{{Username: "a"}}})
+	b.UpdateItem(ctx, Item{Name: "github.com", Credentials: []Credential{{Username: "b"}}})
+
+	err = a.Sync(ctx)
+	if err != nil {
+		t.Fatalf("Sync() error: %v", err)
+	}
+
+	err = b.Sync(ctx)
+	if err == nil || !strings.Contains(err.Error(), "github.com.json") {
+		t.Errorf("Sync() with conflict = %v; wanted conflict on github.com.json", err)
+	}
+}
@@ -0,0 +1,437 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/perryrh0dan/passline/pkg/config"
+	"github.com/perryrh0dan/passline/pkg/crypt"
+	"github.com/perryrh0dan/passline/pkg/kdbx"
+)
+
+const (
+	recoveryCodesField = "Recovery Codes"
+	// Additional urls use the fields of KeePassXC and Keepass2Android
+	urlField      = "KP2A_URL"
+	urlMatchField = "URL Match"
+)
+
+// KeePass stores items in a KDBX 4 database. Every credential is an entry
+// whose title is the item name, so the database can also be used with
+// KeePass or KeePassXC. The database is protected by the global password and
+// the key file of the vault. Their composite key is also used to translate
+// between the plaintext values in the database and the encrypted credentials
+// passline works with.
+type KeePass struct {
+	file    string
+	keyFile string
+	key     []byte
+	db      *kdbx.Database
+}
+
+func init() {
+	Register(Backend{
+		Name:        "kdbx",
+		Description: "KeePass 4 database",
+		Settings:    []string{"Kdbx.File"},
+		New: func(_ context.Context) (Storage, error) {
+			return NewKeePass()
+		},
+	})
+}
+
+func NewKeePass() (*KeePass, error) {
+	cfg, err := config.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	file := cfg.Kdbx.File
+	if file == "" {
+		storageDir := path.Join(cfg.Directory, "storage")
+		ensureDirectories(storageDir, "")
+		file = path.Join(storageDir, "passline.kdbx")
+	}
+
+	return &KeePass{file: file, keyFile: cfg.KeyFile}, nil
+}
+
+// open decrypts the database on first use
+func (kp *KeePass) open() error {
+	if kp.db != nil {
+		return nil
+	}
+
+	if PasswordPrompt == nil {
+		return errors.New("No password prompt available")
+	}
+	password := PasswordPrompt()
+
+	var keyFile []byte
+	kp.key = password
+	if kp.keyFile != "" {
+		var err error
+		keyFile, err = ioutil.ReadFile(kp.keyFile)
+		if err != nil {
+			return errors.New("Unable to read key file " + kp.keyFile)
+		}
+		kp.key = crypt.CompositeKey(password, keyFile)
+	}
+
+	var err error
+	_, err = os.Stat(kp.file)
+	if os.IsNotExist(err) {
+		kp.db, err = kdbx.New(password, keyFile)
+		return err
+	}
+
+	kp.db, err = kdbx.Open(kp.file, password, keyFile)
+	return err
+}
+
+func (kp *KeePass) GetItemByName(ctx context.Context, name string) (Item, error) {
+	items, err := kp.GetAllItems(ctx)
+	if err != nil {
+		return Item{}, err
+	}
+
+	for _, item := range items {
+		if item.Name == name {
+			return item, nil
+		}
+	}
+
+	return Item{}, errors.New("Item not found")
+}
+
+func (kp *KeePass) GetItemByIndex(ctx context.Context, index int) (Item, error) {
+	items, err := kp.GetAllItems(ctx)
+	if err != nil {
+		return Item{}, err
+	}
+
+	if index < 0 || index >= len(items) {
+		return Item{}, errors.New("Out of index")
+	}
+
+	return items[index], nil
+}
+
+func (kp *KeePass) GetAllItems(ctx context.Context) ([]Item, error) {
+	err := kp.open()
+	if err != nil {
+		return nil, err
+	}
+
+	items := []Item{}
+	index := map[string]int{}
+
+	for _, entry := range kp.db.Entries() {
+		name := entryName(entry)
+		if name == "" {
+			continue
+		}
+
+		credential, err := kp.toCredential(entry)
+		if err != nil {
+			return nil, err
+		}
+
+		i, ok := index[name]
+		if !ok {
+			index[name] = len(items)
+			items = append(items, Item{Name: name, Folder: entry.Path(), Tags: entry.Tags(), URIs: entryURIs(entry), Credentials: []Credential{credential}})
+			continue
+		}
+		items[i].Tags = CleanTags(append(items[i].Tags, entry.Tags()...))
+		items[i].Credentials = append(items[i].Credentials, credential)
+	}
+
+	sort.Sort(ByName(items))
+	return items, nil
+}
+
+func (kp *KeePass) CreateItem(ctx context.Context, item Item) error {
+	err := kp.open()
+	if err != nil {
+		return err
+	}
+
+	for _, credential := range item.Credentials {
+		err = kp.fromItem(kp.db.AddEntry(), item, credential)
+		if err != nil {
+			return err
+		}
+	}
+
+	return kp.db.Save(kp.file)
+}
+
+func (kp *KeePass) AddCredential(ctx context.Context, name string, credential Credential) error {
+	err := kp.open()
+	if err != nil {
+		return err
+	}
+
+	item := Item{Name: name}
+	for i, entry := range kp.entries(name) {
+		if entry.Get("UserName") == credential.Username {
+			return errors.New("Username already exists")
+		}
+
+		// New entries are placed next to the existing ones
+		if i == 0 {
+			item.Folder = entry.Path()
+			item.Tags = entry.Tags()
+		}
+	}
+
+	err = kp.fromItem(kp.db.AddEntry(), item, credential)
+	if err != nil {
+		return err
+	}
+
+	return kp.db.Save(kp.file)
+}
+
+func (kp *KeePass) DeleteCredential(ctx context.Context, item Item, credential Credential) error {
+	err := kp.open()
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range kp.entries(item.Name) {
+		if entry.Get("UserName") == credential.Username {
+			kp.db.RemoveEntry(entry)
+			return kp.db.Save(kp.file)
+		}
+	}
+
+	return errors.New("Item not found")
+}
+
+// UpdateItem updates the entries of the item in place, so fields passline
+// does not know about are kept
+func (kp *KeePass) UpdateItem(ctx context.Context, item Item) error {
+	err := kp.open()
+	if err != nil {
+		return err
+	}
+
+	entries := kp.entries(item.Name)
+	for i, credential := range item.Credentials {
+		var entry *kdbx.Entry
+		if i < len(entries) {
+			entry = entries[i]
+		} else {
+			entry = kp.db.AddEntry()
+		}
+
+		err = kp.fromItem(entry, item, credential)
+		if err != nil {
+			return err
+		}
+	}
+
+	for i := len(item.Credentials); i < len(entries); i++ {
+		kp.db.RemoveEntry(entries[i])
+	}
+
+	return kp.db.Save(kp.file)
+}
+
+func (kp *KeePass) SetData(ctx context.Context, data Data) error {
+	err := kp.open()
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range kp.db.Entries() {
+		kp.db.RemoveEntry(entry)
+	}
+
+	for _, item := range data.Items {
+		for _, credential := range item.Credentials {
+			err = kp.fromItem(kp.db.AddEntry(), item, credential)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return kp.db.Save(kp.file)
+}
+
+func (kp *KeePass) entries(name string) []*kdbx.Entry {
+	entries := []*kdbx.Entry{}
+	for _, entry := range kp.db.Entries() {
+		if entryName(entry) == name {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries
+}
+
+// toCredential encrypts the plaintext values of an entry
+func (kp *KeePass) toCredential(entry *kdbx.Entry) (Credential, error) {
+	password, err := crypt.AesGcmEncrypt(kp.key, entry.Get("Password"))
+	if err != nil {
+		return Credential{}, err
+	}
+
+	recoveryCodes := []string{}
+	if codes := entry.Get(recoveryCodesField); codes != "" {
+		for _, code := range strings.Split(codes, ",") {
+			encrypted, err := crypt.AesGcmEncrypt(kp.key, code)
+			if err != nil {
+				return Credential{}, err
+			}
+			recoveryCodes = append(recoveryCodes, encrypted)
+		}
+	}
+
+	created, modified := entry.Times()
+	return Credential{Username: entry.Get("UserName"), Password: password, RecoveryCodes: recoveryCodes, Created: created, Modified: modified}, nil
+}
+
+// fromCredential decrypts a credential into the fields of an entry
+func (kp *KeePass) fromCredential(entry *kdbx.Entry, name string, credential Credential) error {
+	password, err := crypt.AesGcmDecrypt(kp.key, credential.Password)
+	if err != nil {
+		return err
+	}
+
+	recoveryCodes := []string{}
+	for _, code := range credential.RecoveryCodes {
+		decrypted, err := crypt.AesGcmDecrypt(kp.key, code)
+		if err != nil {
+			return err
+		}
+		recoveryCodes = append(recoveryCodes, decrypted)
+	}
+
+	if entryName(entry) != name {
+		entry.Set("Title", name, false)
+	}
+	entry.Set("UserName", credential.Username, false)
+	entry.Set("Password", password, true)
+	if len(recoveryCodes) > 0 || entry.Get(recoveryCodesField) != "" {
+		entry.Set(recoveryCodesField, strings.Join(recoveryCodes, ","), true)
+	}
+
+	return nil
+}
+
+// fromItem writes a credential of the item into an entry and moves the entry
+// into the group of the item folder
+func (kp *KeePass) fromItem(entry *kdbx.Entry, item Item, credential Credential) error {
+	err := kp.fromCredential(entry, item.Name, credential)
+	if err != nil {
+		return err
+	}
+
+	// The title is the name once the url can change
+	if entry.Get("Title") == "" {
+		entry.Set("Title", item.Name, false)
+	}
+	setEntryURIs(entry, item.URIs)
+
+	entry.SetTags(item.Tags)
+	kp.db.MoveEntry(entry, item.Folder)
+
+	// Modified is when the password was changed
+	entry.SetTimes(credential.Created, credential.Modified)
+	return nil
+}
+
+// entryURIs reads the url and the additional urls of an entry
+func entryURIs(entry *kdbx.Entry) []URI {
+	values := []string{}
+	if u := entry.Get("URL"); u != "" {
+		values = append(values, u)
+	}
+
+	keys := []string{}
+	for _, key := range entry.Keys() {
+		if strings.HasPrefix(key, urlField) {
+			keys = append(keys, key)
+		}
+	}
+	// KP2A_URL_10 comes after KP2A_URL_9
+	sort.Slice(keys, func(i, j int) bool {
+		return len(keys[i]) < len(keys[j]) || len(keys[i]) == len(keys[j]) && keys[i] < keys[j]
+	})
+
+	for _, key := range keys {
+		if u := entry.Get(key); u != "" {
+			values = append(values, u)
+		}
+	}
+
+	rules := strings.Split(entry.Get(urlMatchField), ",")
+
+	uris := []URI{}
+	for i, value := range values {
+		uri := URI{URI: value}
+		if i < len(rules) && rules[i] != MatchDomain {
+			uri.Match = rules[i]
+		}
+		uris = append(uris, uri)
+	}
+
+	return uris
+}
+
+// setEntryURIs writes the first uri to the url field and the others to
+// additional url fields. Match rules are only kept if one differs from the
+// default.
+func setEntryURIs(entry *kdbx.Entry, uris []URI) {
+	for _, key := range entry.Keys() {
+		if strings.HasPrefix(key, urlField) {
+			entry.Remove(key)
+		}
+	}
+
+	rules := []string{}
+	custom := false
+	for i, uri := range uris {
+		if i == 0 {
+			entry.Set("URL", uri.URI, false)
+		} else {
+			entry.Set(urlField+"_"+strconv.Itoa(i), uri.URI, false)
+		}
+
+		rule := uri.Match
+		if rule == "" {
+			rule = MatchDomain
+		}
+		custom = custom || rule != MatchDomain
+		rules = append(rules, rule)
+	}
+
+	if len(uris) == 0 && entry.Get("URL") != "" {
+		entry.Set("URL", "", false)
+	}
+
+	if custom {
+		entry.Set(urlMatchField, strings.Join(rules, ","), false)
+	} else {
+		entry.Remove(urlMatchField)
+	}
+}
+
+// entryName is the title of an entry or its url if the title is empty
+func entryName(entry *kdbx.Entry) string {
+	if title := entry.Get("Title"); title != "" {
+		return title
+	}
+
+	return entry.Get("URL")
+}
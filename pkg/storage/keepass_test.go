@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/perryrh0dan/passline/pkg/crypt"
+	"github.com/perryrh0dan/passline/pkg/kdbx"
+)
+
+func TestKeePassKeyFile(t *testing.T) {
+	ctx := context.Background()
+	password := []byte("password")
+
+	dir, err := ioutil.TempDir("", "passline-kdbx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	prompt := PasswordPrompt
+	defer func() { PasswordPrompt = prompt }()
+	PasswordPrompt = func() []byte { return password }
+
+	keyFile := filepath.Join(dir, "vault.key")
+	data, _ := crypt.GenerateKeyFile()
+	err = ioutil.WriteFile(keyFile, data, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The credentials are encrypted with the key of password and key file
+	key := crypt.CompositeKey(password, data)
+	encrypted, _ := crypt.AesGcmEncrypt(key, "secret")
+
+	file := filepath.Join(dir, "passline.kdbx")
+	kp := &KeePass{file: file, keyFile: keyFile}
+	err = kp.CreateItem(ctx, Item{Name: "github.com", Credentials: []Credential{{Username: "perry", Password: encrypted}}})
+	if err != nil {
+		t.Fatalf("CreateItem() error: %v", err)
+	}
+
+	item, err := (&KeePass{file: file, keyFile: keyFile}).GetItemByName(ctx, "github.com")
+	if err != nil {
+		t.Fatalf("GetItemByName() error: %v", err)
+	}
+
+	if decrypted, err := crypt.AesGcmDecrypt(key, item.Credentials[0].Password); err != nil || decrypted != "secret" {
+		t.Errorf("GetItemByName() returned a credential that does not decrypt with the key: %v", err)
+	}
+
+	// KeePass opens the database with the same password and key file
+	db, err := kdbx.Open(file, password, data)
+	if err != nil || db.Entries()[0].Get("Password") != "secret" {
+		t.Errorf("kdbx.Open() with the key file = %v; wanted the plaintext entry", err)
+	}
+
+	if _, err := kdbx.Open(file, password, nil); err == nil {
+		t.Errorf("kdbx.Open() without the key file succeeded")
+	}
+}
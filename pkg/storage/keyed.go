@@ -0,0 +1,141 @@
+package storage
+
+import (
+	"context"
+	"errors"
+)
+
+// MembersItem holds the vault key of a team vault wrapped for every member.
+// The username of a credential is the name of the member, the password the
+// wrapped key and the only recovery code the public key of the member.
+const MembersItem = ".members"
+
+// KeyItem holds the vault key of an age vault as the password of its only
+// credential, encrypted to the age recipients of the vault
+const KeyItem = ".key"
+
+// Keyed hides the items with the vault key of a team or age vault from the
+// other items
+type Keyed struct {
+	Storage
+}
+
+func NewKeyed(s Storage) *Keyed {
+	return &Keyed{Storage: s}
+}
+
+// Unwrap returns the storage of the vault
+func (t *Keyed) Unwrap() Storage {
+	return t.Storage
+}
+
+func isKeyItem(name string) bool {
+	return name == MembersItem || name == KeyItem
+}
+
+func (t *Keyed) GetItemByName(ctx context.Context, name string) (Item, error) {
+	if isKeyItem(name) {
+		return Item{}, errors.New("Item not found")
+	}
+
+	return t.Storage.GetItemByName(ctx, name)
+}
+
+func (t *Keyed) GetItemByIndex(ctx context.Context, index int) (Item, error) {
+	items, err := t.GetAllItems(ctx)
+	if err != nil {
+		return Item{}, err
+	}
+
+	if index < 0 || index >= len(items) {
+		return Item{}, errors.New("Out of index")
+	}
+
+	return items[index], nil
+}
+
+func (t *Keyed) GetAllItems(ctx context.Context) ([]Item, error) {
+	all, err := t.Storage.GetAllItems(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	items := []Item{}
+	for _, item := range all {
+		if !isKeyItem(item.Name) {
+			items = append(items, item)
+		}
+	}
+
+	return items, nil
+}
+
+func (t *Keyed) CreateItem(ctx context.Context, item Item) error {
+	if isKeyItem(item.Name) {
+		return errors.New("Item name " + item.Name + " is reserved")
+	}
+
+	return t.Storage.CreateItem(ctx, item)
+}
+
+// SetData keeps the current key items unless data contains them
+func (t *Keyed) SetData(ctx context.Context, data Data) error {
+	for _, name := range []string{MembersItem, KeyItem} {
+		found := false
+		for _, item := range data.Items {
+			if item.Name == name {
+				found = true
+			}
+		}
+
+		if found {
+			continue
+		}
+
+		item, err := t.Storage.GetItemByName(ctx, name)
+		if err == nil {
+			data.Items = append(data.Items, item)
+		}
+	}
+
+	return t.Storage.SetData(ctx, data)
+}
+
+// Members returns the members item
+func (t *Keyed) Members(ctx context.Context) (Item, error) {
+	return t.Storage.GetItemByName(ctx, MembersItem)
+}
+
+// Key returns the key item
+func (t *Keyed) Key(ctx context.Context) (Item, error) {
+	return t.Storage.GetItemByName(ctx, KeyItem)
+}
+
+// SetKey creates or replaces the key item
+func (t *Keyed) SetKey(ctx context.Context, key Credential) error {
+	item := Item{Name: KeyItem, Credentials: []Credential{key}}
+
+	_, err := t.Key(ctx)
+	if err != nil {
+		return t.Storage.CreateItem(ctx, item)
+	}
+
+	return t.Storage.UpdateItem(ctx, item)
+}
+
+// AddMember adds the wrapped vault key of a member and creates the members
+// item for the first one
+func (t *Keyed) AddMember(ctx context.Context, member Credential) error {
+	_, err := t.Members(ctx)
+	if err != nil {
+		return t.Storage.CreateItem(ctx, Item{Name: MembersItem, Credentials: []Credential{member}})
+	}
+
+	return t.Storage.AddCredential(ctx, MembersItem, member)
+}
+
+// Member of a team vault
+type Member struct {
+	Name      string
+	PublicKey string
+}
@@ -15,6 +15,16 @@ type LocalStorage struct {
 	storageFile string
 }
 
+func init() {
+	Register(Backend{
+		Name:        "local",
+		Description: "Encrypted json file in the passline directory",
+		New: func(_ context.Context) (Storage, error) {
+			return NewLocalStorage()
+		},
+	})
+}
+
 func NewLocalStorage() (*LocalStorage, error) {
 	mainDir, _ := getMainDir()
 
@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"sort"
+)
+
+// Backend describes a storage that can be selected with the Storage setting
+// of the config. The settings of a backend are a static section of
+// config.Config and config.Vault, a backend with new settings has to add its
+// section there.
+type Backend struct {
+	Name        string
+	Description string
+	// Keys of the config file the backend is configured with
+	Settings []string
+	// Remote backends can be wrapped with the offline cache
+	Remote bool
+	New    func(context.Context) (Storage, error)
+}
+
+var backends = map[string]Backend{}
+
+// Register makes a backend available, it panics if the name is taken
+func Register(backend Backend) {
+	if _, ok := backends[backend.Name]; ok {
+		panic("storage: backend " + backend.Name + " registered twice")
+	}
+
+	backends[backend.Name] = backend
+}
+
+// Lookup returns the backend with the given name
+func Lookup(name string) (Backend, error) {
+	backend, ok := backends[name]
+	if !ok {
+		return Backend{}, errors.New("Unknown storage " + name)
+	}
+
+	return backend, nil
+}
+
+// Backends returns all registered backends sorted by name
+func Backends() []Backend {
+	list := []Backend{}
+	for _, backend := range backends {
+		list = append(list, backend)
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// BackendNames returns the names of all registered backends
+func BackendNames() []string {
+	names := []string{}
+	for _, backend := range Backends() {
+		names = append(names, backend.Name)
+	}
+
+	return names
+}
@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/perryrh0dan/passline/pkg/config"
+)
+
+func TestRegistry(t *testing.T) {
+	for _, name := range []string{"local", "firestore", "kdbx", "git", "sqlite", "s3", "webdav", "remote"} {
+		backend, err := Lookup(name)
+		if err != nil || backend.New == nil {
+			t.Errorf("Lookup(%q) = %+v, %v", name, backend, err)
+		}
+	}
+
+	if _, err := Lookup("unknown"); err == nil {
+		t.Errorf("Lookup(\"unknown\") succeeded")
+	}
+
+	backend, _ := Lookup("sqlite")
+	if settings := backend.Settings; len(settings) != 1 || settings[0] != "Sqlite.File" {
+		t.Errorf("Settings() = %v; wanted [Sqlite.File]", settings)
+	}
+}
+
+func TestBackendSettings(t *testing.T) {
+	// Every listed setting is a field of the config
+	for _, backend := range Backends() {
+		for _, setting := range backend.Settings {
+			parts := strings.Split(setting, ".")
+			section, ok := reflect.TypeOf(config.Config{}).FieldByName(parts[0])
+			if !ok || len(parts) != 2 {
+				t.Errorf("Setting %s of %s is not a config section", setting, backend.Name)
+				continue
+			}
+
+			if _, ok := section.Type.FieldByName(parts[1]); !ok {
+				t.Errorf("Setting %s of %s is not a config field", setting, backend.Name)
+			}
+		}
+	}
+}
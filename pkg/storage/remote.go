@@ -0,0 +1,249 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/perryrh0dan/passline/pkg/config"
+	"github.com/perryrh0dan/passline/pkg/server"
+)
+
+const remoteRetries = 3
+
+var errRemoteConflict = errors.New("Item was changed by another device, please try again")
+
+// Remote stores items on a passline sync server
+type Remote struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+func init() {
+	Register(Backend{
+		Name:        "remote",
+		Description: "Passline sync server",
+		Settings:    []string{"Remote.URL", "Remote.Token"},
+		Remote:      true,
+		New: func(_ context.Context) (Storage, error) {
+			return NewRemote()
+		},
+	})
+}
+
+func NewRemote() (*Remote, error) {
+	cfg, err := config.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	return newRemote(cfg.Remote.URL, cfg.Remote.Token)
+}
+
+func newRemote(serverURL, token string) (*Remote, error) {
+	if serverURL == "" {
+		return nil, errors.New("Remote url is required")
+	}
+
+	return &Remote{
+		url:    strings.TrimSuffix(serverURL, "/"),
+		token:  token,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+func (r *Remote) GetItemByName(ctx context.Context, name string) (Item, error) {
+	item, _, err := r.getItem(ctx, name)
+	return item, err
+}
+
+func (r *Remote) GetItemByIndex(ctx context.Context, index int) (Item, error) {
+	items, err := r.GetAllItems(ctx)
+	if err != nil {
+		return Item{}, err
+	}
+
+	if index < 0 || index >= len(items) {
+		return Item{}, errors.New("Out of index")
+	}
+
+	return items[index], nil
+}
+
+func (r *Remote) GetAllItems(ctx context.Context) ([]Item, error) {
+	list := server.ItemList{}
+	err := r.request(ctx, http.MethodGet, "/v1/items", nil, &list)
+	if err != nil {
+		return nil, err
+	}
+
+	items := []Item{}
+	for _, remoteItem := range list.Items {
+		item := Item{}
+		err = json.Unmarshal(remoteItem.Data, &item)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+func (r *Remote) CreateItem(ctx context.Context, item Item) error {
+	return r.putItem(ctx, item, 0)
+}
+
+func (r *Remote) AddCredential(ctx context.Context, name string, credential Credential) error {
+	return r.modify(ctx, name, func(item *Item) (bool, error) {
+		if getIndexOfCredential(item.Credentials, credential) != -1 {
+			return false, errors.New("Username already exists")
+		}
+
+		item.Credentials = append(item.Credentials, credential)
+		return true, nil
+	})
+}
+
+func (r *Remote) DeleteCredential(ctx context.Context, item Item, credential Credential) error {
+	return r.modify(ctx, item.Name, func(item *Item) (bool, error) {
+		index := getIndexOfCredential(item.Credentials, credential)
+		if index == -1 {
+			return false, errors.New("Item not found")
+		}
+
+		if len(item.Credentials) == 1 {
+			return false, nil
+		}
+
+		item.Credentials = removeFromCredentials(item.Credentials, index)
+		return true, nil
+	})
+}
+
+func (r *Remote) UpdateItem(ctx context.Context, item Item) error {
+	return r.modify(ctx, item.Name, func(current *Item) (bool, error) {
+		*current = item
+		return true, nil
+	})
+}
+
+func (r *Remote) SetData(ctx context.Context, data Data) error {
+	list := server.ItemList{Items: []server.RemoteItem{}}
+	for _, item := range data.Items {
+		raw, err := json.Marshal(item)
+		if err != nil {
+			return err
+		}
+		list.Items = append(list.Items, server.RemoteItem{Name: item.Name, Data: raw})
+	}
+
+	return r.request(ctx, http.MethodPut, "/v1/items", list, nil)
+}
+
+// modify applies fn to the latest revision of the item and writes it back.
+// If fn returns false the item is deleted.
+func (r *Remote) modify(ctx context.Context, name string, fn func(*Item) (bool, error)) error {
+	for i := 0; i < remoteRetries; i++ {
+		item, revision, err := r.getItem(ctx, name)
+		if err != nil {
+			return err
+		}
+
+		keep, err := fn(&item)
+		if err != nil {
+			return err
+		}
+
+		if keep {
+			err = r.putItem(ctx, item, revision)
+		} else {
+			err = r.request(ctx, http.MethodDelete, itemPath(name)+"?revision="+strconv.FormatInt(revision, 10), nil, nil)
+		}
+
+		if err != errRemoteConflict {
+			return err
+		}
+	}
+
+	return errRemoteConflict
+}
+
+func (r *Remote) getItem(ctx context.Context, name string) (Item, int64, error) {
+	remoteItem := server.RemoteItem{}
+	err := r.request(ctx, http.MethodGet, itemPath(name), nil, &remoteItem)
+	if err != nil {
+		return Item{}, 0, err
+	}
+
+	item := Item{}
+	err = json.Unmarshal(remoteItem.Data, &item)
+	return item, remoteItem.Revision, err
+}
+
+func (r *Remote) putItem(ctx context.Context, item Item, revision int64) error {
+	raw, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+
+	return r.request(ctx, http.MethodPut, itemPath(item.Name), server.RemoteItem{Name: item.Name, Revision: revision, Data: raw}, nil)
+}
+
+func (r *Remote) request(ctx context.Context, method, path string, body interface{}, result interface{}) error {
+	var data []byte
+	if body != nil {
+		var err error
+		data, err = json.Marshal(body)
+		if err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest(method, r.url+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Authorization", "Bearer "+r.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	resBody, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case res.StatusCode == http.StatusNotFound:
+		return errors.New("Item not found")
+	case res.StatusCode == http.StatusConflict:
+		return errRemoteConflict
+	case res.StatusCode >= 300:
+		return fmt.Errorf("Remote request failed with status %s: %s", res.Status, strings.TrimSpace(string(resBody)))
+	}
+
+	if result != nil {
+		return json.Unmarshal(resBody, result)
+	}
+
+	return nil
+}
+
+func itemPath(name string) string {
+	return "/v1/items/" + url.PathEscape(name)
+}
@@ -0,0 +1,223 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/perryrh0dan/passline/pkg/config"
+)
+
+// S3 stores the encrypted vault as one object in an S3 compatible bucket
+type S3 struct {
+	blobStorage
+}
+
+// s3Client is a minimal S3 client that signs requests with AWS signature v4
+type s3Client struct {
+	endpoint  *url.URL
+	region    string
+	bucket    string
+	key       string
+	accessKey string
+	secretKey string
+	pathStyle bool
+	client    *http.Client
+}
+
+func init() {
+	Register(Backend{
+		Name:        "s3",
+		Description: "Object in a S3 compatible bucket",
+		Settings:    []string{"S3.Endpoint", "S3.Region", "S3.Bucket", "S3.Key", "S3.AccessKey", "S3.SecretKey", "S3.PathStyle"},
+		Remote:      true,
+		New: func(_ context.Context) (Storage, error) {
+			return NewS3()
+		},
+	})
+}
+
+func NewS3() (*S3, error) {
+	cfg, err := config.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	return newS3(cfg.S3)
+}
+
+func newS3(cfg config.S3) (*S3, error) {
+	if cfg.Endpoint == "" || cfg.Bucket == "" {
+		return nil, errors.New("S3 endpoint and bucket are required")
+	}
+
+	endpoint, err := url.Parse(cfg.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &s3Client{
+		endpoint:  endpoint,
+		region:    cfg.Region,
+		bucket:    cfg.Bucket,
+		key:       cfg.Key,
+		accessKey: cfg.AccessKey,
+		secretKey: cfg.SecretKey,
+		pathStyle: cfg.PathStyle,
+		client:    &http.Client{Timeout: 30 * time.Second},
+	}
+
+	if client.region == "" {
+		client.region = "us-east-1"
+	}
+
+	if client.key == "" {
+		client.key = "passline/storage.json"
+	}
+
+	return &S3{blobStorage{store: client}}, nil
+}
+
+func (c *s3Client) get(ctx context.Context) ([]byte, string, error) {
+	res, err := c.do(ctx, http.MethodGet, nil, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, "", err
+	}
+
+	switch res.StatusCode {
+	case http.StatusOK:
+		return body, res.Header.Get("ETag"), nil
+	case http.StatusNotFound:
+		return nil, "", errBlobNotFound
+	}
+
+	return nil, "", s3Error(res, body)
+}
+
+func (c *s3Client) put(ctx context.Context, data []byte, etag string) error {
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	if etag == "" {
+		header.Set("If-None-Match", "*")
+	} else {
+		header.Set("If-Match", etag)
+	}
+
+	res, err := c.do(ctx, http.MethodPut, data, header)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	body, _ := ioutil.ReadAll(res.Body)
+
+	switch res.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusPreconditionFailed, http.StatusConflict:
+		return errPreconditionFailed
+	}
+
+	return s3Error(res, body)
+}
+
+func (c *s3Client) do(ctx context.Context, method string, body []byte, header http.Header) (*http.Response, error) {
+	u := *c.endpoint
+	if c.pathStyle {
+		u.Path = "/" + c.bucket + "/" + c.key
+	} else {
+		u.Host = c.bucket + "." + u.Host
+		u.Path = "/" + c.key
+	}
+
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+
+	for key, values := range header {
+		req.Header[key] = values
+	}
+
+	c.sign(req, body, time.Now().UTC())
+	return c.client.Do(req)
+}
+
+// sign adds an AWS signature version 4 to the request
+func (c *s3Client) sign(req *http.Request, body []byte, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for key, values := range req.Header {
+		headers[strings.ToLower(key)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.Query().Encode(),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + c.region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+c.secretKey), date)
+	key = hmacSHA256(key, c.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", c.accessKey, scope, signedHeaders, signature))
+}
+
+func s3Error(res *http.Response, body []byte) error {
+	return fmt.Errorf("S3 request failed with status %s: %s", res.Status, strings.TrimSpace(string(body)))
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
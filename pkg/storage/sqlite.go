@@ -0,0 +1,460 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path"
+	"strconv"
+	"time"
+
+	// Register the sqlite driver, it is pure Go and needs no cgo
+	_ "modernc.org/sqlite"
+
+	"github.com/perryrh0dan/passline/pkg/config"
+)
+
+// migrations are applied in order, the schema version in the metadata table
+// is the number of applied migrations
+var migrations = []string{
+	`CREATE TABLE items (
+		id INTEGER PRIMARY KEY,
+		name TEXT NOT NULL UNIQUE
+	);
+	CREATE TABLE credentials (
+		id INTEGER PRIMARY KEY,
+		item_id INTEGER NOT NULL REFERENCES items(id) ON DELETE CASCADE,
+		username TEXT NOT NULL,
+		password TEXT NOT NULL,
+		UNIQUE(item_id, username)
+	);
+	CREATE TABLE recovery_codes (
+		id INTEGER PRIMARY KEY,
+		credential_id INTEGER NOT NULL REFERENCES credentials(id) ON DELETE CASCADE,
+		position INTEGER NOT NULL,
+		code TEXT NOT NULL
+	);`,
+	`ALTER TABLE items ADD COLUMN folder TEXT NOT NULL DEFAULT '';
+	CREATE TABLE tags (
+		id INTEGER PRIMARY KEY,
+		item_id INTEGER NOT NULL REFERENCES items(id) ON DELETE CASCADE,
+		position INTEGER NOT NULL,
+		tag TEXT NOT NULL
+	);`,
+	`CREATE TABLE uris (
+		id INTEGER PRIMARY KEY,
+		item_id INTEGER NOT NULL REFERENCES items(id) ON DELETE CASCADE,
+		position INTEGER NOT NULL,
+		uri TEXT NOT NULL,
+		match TEXT NOT NULL
+	);`,
+	`ALTER TABLE credentials ADD COLUMN created INTEGER;
+	ALTER TABLE credentials ADD COLUMN modified INTEGER;`,
+}
+
+// SQLite stores items in a sqlite database
+type SQLite struct {
+	db *sql.DB
+}
+
+func init() {
+	Register(Backend{
+		Name:        "sqlite",
+		Description: "Sqlite database",
+		Settings:    []string{"Sqlite.File"},
+		New: func(_ context.Context) (Storage, error) {
+			return NewSQLite()
+		},
+	})
+}
+
+func NewSQLite() (*SQLite, error) {
+	cfg, err := config.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	file := cfg.Sqlite.File
+	if file == "" {
+		storageDir := path.Join(cfg.Directory, "storage")
+		ensureDirectories(storageDir, "")
+		file = path.Join(storageDir, "storage.db")
+	}
+
+	return newSQLite(file)
+}
+
+func newSQLite(file string) (*SQLite, error) {
+	db, err := sql.Open("sqlite", "file:"+file+"?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, err
+	}
+
+	s := &SQLite{db: db}
+	err = s.migrate()
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *SQLite) migrate() error {
+	_, err := s.db.Exec(`CREATE TABLE IF NOT EXISTS metadata (key TEXT PRIMARY KEY, value TEXT NOT NULL)`)
+	if err != nil {
+		return err
+	}
+
+	return s.transaction(func(tx *sql.Tx) error {
+		version := 0
+		var value string
+		err := tx.QueryRow(`SELECT value FROM metadata WHERE key = 'schema_version'`).Scan(&value)
+		if err == nil {
+			version, err = strconv.Atoi(value)
+		}
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+
+		if version > len(migrations) {
+			return errors.New("Database schema is newer than this version of passline")
+		}
+
+		for _, migration := range migrations[version:] {
+			_, err = tx.Exec(migration)
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err = tx.Exec(`INSERT OR REPLACE INTO metadata (key, value) VALUES ('schema_version', ?)`, strconv.Itoa(len(migrations)))
+		return err
+	})
+}
+
+func (s *SQLite) GetItemByName(ctx context.Context, name string) (Item, error) {
+	items, err := s.queryItems(ctx, `WHERE i.name = ?`, name)
+	if err != nil {
+		return Item{}, err
+	}
+
+	if len(items) == 0 {
+		return Item{}, errors.New("Item not found")
+	}
+
+	return items[0], nil
+}
+
+func (s *SQLite) GetItemByIndex(ctx context.Context, index int) (Item, error) {
+	items, err := s.queryItems(ctx, `WHERE i.id = (SELECT id FROM items ORDER BY name LIMIT 1 OFFSET ?)`, index)
+	if err != nil {
+		return Item{}, err
+	}
+
+	if index < 0 || len(items) == 0 {
+		return Item{}, errors.New("Out of index")
+	}
+
+	return items[0], nil
+}
+
+func (s *SQLite) GetAllItems(ctx context.Context) ([]Item, error) {
+	return s.queryItems(ctx, "")
+}
+
+func (s *SQLite) CreateItem(ctx context.Context, item Item) error {
+	return s.transaction(func(tx *sql.Tx) error {
+		return insertItem(ctx, tx, item)
+	})
+}
+
+func (s *SQLite) AddCredential(ctx context.Context, name string, credential Credential) error {
+	return s.transaction(func(tx *sql.Tx) error {
+		var itemID int64
+		err := tx.QueryRowContext(ctx, `SELECT id FROM items WHERE name = ?`, name).Scan(&itemID)
+		if err == sql.ErrNoRows {
+			return errors.New("Item not found")
+		}
+		if err != nil {
+			return err
+		}
+
+		var count int
+		err = tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM credentials WHERE item_id = ? AND username = ?`, itemID, credential.Username).Scan(&count)
+		if err != nil {
+			return err
+		}
+
+		if count > 0 {
+			return errors.New("Username already exists")
+		}
+
+		return insertCredential(ctx, tx, itemID, credential)
+	})
+}
+
+func (s *SQLite) DeleteCredential(ctx context.Context, item Item, credential Credential) error {
+	return s.transaction(func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, `DELETE FROM credentials WHERE username = ? AND item_id = (SELECT id FROM items WHERE name = ?)`, credential.Username, item.Name)
+		if err != nil {
+			return err
+		}
+
+		if n, _ := res.RowsAffected(); n == 0 {
+			return errors.New("Item not found")
+		}
+
+		// Remove the item with its last credential
+		_, err = tx.ExecContext(ctx, `DELETE FROM items WHERE name = ? AND NOT EXISTS (SELECT 1 FROM credentials WHERE item_id = items.id)`, item.Name)
+		return err
+	})
+}
+
+func (s *SQLite) UpdateItem(ctx context.Context, item Item) error {
+	return s.transaction(func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `DELETE FROM items WHERE name = ?`, item.Name)
+		if err != nil {
+			return err
+		}
+
+		return insertItem(ctx, tx, item)
+	})
+}
+
+func (s *SQLite) SetData(ctx context.Context, data Data) error {
+	return s.transaction(func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `DELETE FROM items`)
+		if err != nil {
+			return err
+		}
+
+		for _, item := range data.Items {
+			err = insertItem(ctx, tx, item)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// transaction runs fn in a transaction that is rolled back if fn fails
+func (s *SQLite) transaction(fn func(*sql.Tx) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	err = fn(tx)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// queryItems loads the items matching the where clause sorted by name
+func (s *SQLite) queryItems(ctx context.Context, where string, args ...interface{}) ([]Item, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT i.name, i.folder, c.id, c.username, c.password, c.created, c.modified, r.code
+		FROM items i
+		JOIN credentials c ON c.item_id = i.id
+		LEFT JOIN recovery_codes r ON r.credential_id = c.id
+		`+where+`
+		ORDER BY i.name, c.id, r.position`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []Item{}
+	lastCredential := int64(-1)
+	for rows.Next() {
+		var name, folder, username, password string
+		var credentialID int64
+		var created, modified sql.NullInt64
+		var code sql.NullString
+
+		err = rows.Scan(&name, &folder, &credentialID, &username, &password, &created, &modified, &code)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(items) == 0 || items[len(items)-1].Name != name {
+			items = append(items, Item{Name: name, Folder: folder, Credentials: []Credential{}})
+		}
+		item := &items[len(items)-1]
+
+		if credentialID != lastCredential {
+			lastCredential = credentialID
+			item.Credentials = append(item.Credentials, Credential{
+				Username:      username,
+				Password:      password,
+				RecoveryCodes: []string{},
+				Created:       fromUnix(created),
+				Modified:      fromUnix(modified),
+			})
+		}
+
+		if code.Valid {
+			credential := &item.Credentials[len(item.Credentials)-1]
+			credential.RecoveryCodes = append(credential.RecoveryCodes, code.String)
+		}
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.queryTags(ctx, items, where, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return items, s.queryURIs(ctx, items, where, args...)
+}
+
+// queryURIs adds the uris of the items matching the where clause
+func (s *SQLite) queryURIs(ctx context.Context, items []Item, where string, args ...interface{}) error {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT i.name, u.uri, u.match
+		FROM items i
+		JOIN uris u ON u.item_id = i.id
+		`+where+`
+		ORDER BY i.name, u.position`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	index := map[string]int{}
+	for i, item := range items {
+		index[item.Name] = i
+	}
+
+	for rows.Next() {
+		var name string
+		var uri URI
+		err = rows.Scan(&name, &uri.URI, &uri.Match)
+		if err != nil {
+			return err
+		}
+
+		if i, ok := index[name]; ok {
+			items[i].URIs = append(items[i].URIs, uri)
+		}
+	}
+
+	return rows.Err()
+}
+
+// queryTags adds the tags of the items matching the where clause
+func (s *SQLite) queryTags(ctx context.Context, items []Item, where string, args ...interface{}) error {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT i.name, t.tag
+		FROM items i
+		JOIN tags t ON t.item_id = i.id
+		`+where+`
+		ORDER BY i.name, t.position`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	index := map[string]int{}
+	for i, item := range items {
+		index[item.Name] = i
+	}
+
+	for rows.Next() {
+		var name, tag string
+		err = rows.Scan(&name, &tag)
+		if err != nil {
+			return err
+		}
+
+		if i, ok := index[name]; ok {
+			items[i].Tags = append(items[i].Tags, tag)
+		}
+	}
+
+	return rows.Err()
+}
+
+func insertItem(ctx context.Context, tx *sql.Tx, item Item) error {
+	res, err := tx.ExecContext(ctx, `INSERT INTO items (name, folder) VALUES (?, ?)`, item.Name, item.Folder)
+	if err != nil {
+		return err
+	}
+
+	itemID, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	for i, tag := range item.Tags {
+		_, err = tx.ExecContext(ctx, `INSERT INTO tags (item_id, position, tag) VALUES (?, ?, ?)`, itemID, i, tag)
+		if err != nil {
+			return err
+		}
+	}
+
+	for i, uri := range item.URIs {
+		_, err = tx.ExecContext(ctx, `INSERT INTO uris (item_id, position, uri, match) VALUES (?, ?, ?, ?)`, itemID, i, uri.URI, uri.Match)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, credential := range item.Credentials {
+		err = insertCredential(ctx, tx, itemID, credential)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func insertCredential(ctx context.Context, tx *sql.Tx, itemID int64, credential Credential) error {
+	res, err := tx.ExecContext(ctx, `INSERT INTO credentials (item_id, username, password, created, modified) VALUES (?, ?, ?, ?, ?)`,
+		itemID, credential.Username, credential.Password, toUnix(credential.Created), toUnix(credential.Modified))
+	if err != nil {
+		return err
+	}
+
+	credentialID, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	for i, code := range credential.RecoveryCodes {
+		_, err = tx.ExecContext(ctx, `INSERT INTO recovery_codes (credential_id, position, code) VALUES (?, ?, ?)`, credentialID, i, code)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// toUnix stores zero times as NULL
+func toUnix(t time.Time) sql.NullInt64 {
+	if t.IsZero() {
+		return sql.NullInt64{}
+	}
+
+	return sql.NullInt64{Int64: t.Unix(), Valid: true}
+}
+
+func fromUnix(t sql.NullInt64) time.Time {
+	if !t.Valid {
+		return time.Time{}
+	}
+
+	return time.Unix(t.Int64, 0).UTC()
+}
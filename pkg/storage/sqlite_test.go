@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSQLite(t *testing.T) {
+	ctx := context.Background()
+
+	dir, err := ioutil.TempDir("", "passline-sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s, err := newSQLite(filepath.Join(dir, "storage.db"))
+	if err != nil {
+		t.Fatalf("newSQLite() error: %v", err)
+	}
+
+	perry := Credential{Username: "perry", Password: "encrypted", RecoveryCodes: []string{"a", "b"}}
+	other := Credential{Username: "other", Password: "encrypted", RecoveryCodes: []string{}}
+
+	s.CreateItem(ctx, Item{Name: "twitter.com", Credentials: []Credential{other}})
+	s.CreateItem(ctx, Item{Name: "github.com", Folder: "dev/code", Tags: []string{"work", "oss"}, URIs: []URI{{URI: "github.com"}, {URI: "gist.github.com", Match: MatchHost}}, Credentials: []Credential{perry}})
+
+	err = s.AddCredential(ctx, "github.com", other)
+	if err != nil {
+		t.Fatalf("AddCredential() error: %v", err)
+	}
+
+	if err = s.AddCredential(ctx, "github.com", other); err == nil {
+		t.Errorf("AddCredential() with existing username succeeded")
+	}
+
+	item, err := s.GetItemByIndex(ctx, 0)
+	if err != nil || item.Name != "github.com" || len(item.Credentials) != 2 || len(item.Credentials[0].RecoveryCodes) != 2 {
+		t.Errorf("GetItemByIndex(0) = %+v, %v", item, err)
+	}
+
+	if item.Folder != "dev/code" || len(item.Tags) != 2 || item.Tags[0] != "work" {
+		t.Errorf("GetItemByIndex(0) folder and tags = %q, %v; wanted dev/code, [work oss]", item.Folder, item.Tags)
+	}
+
+	if len(item.URIs) != 2 || item.URIs[1].Match != MatchHost {
+		t.Errorf("GetItemByIndex(0) uris = %v; wanted github.com and host:gist.github.com", item.URIs)
+	}
+
+	err = s.DeleteCredential(ctx, Item{Name: "twitter.com"}, other)
+	if err != nil {
+		t.Fatalf("DeleteCredential() error: %v", err)
+	}
+
+	items, _ := s.GetAllItems(ctx)
+	if len(items) != 1 {
+		t.Errorf("GetAllItems() after deleting the last credential returned %d items; wanted 1", len(items))
+	}
+
+	// Reopening does not apply migrations twice
+	s.db.Close()
+	_, err = newSQLite(filepath.Join(dir, "storage.db"))
+	if err != nil {
+		t.Errorf("newSQLite() on existing database error: %v", err)
+	}
+}
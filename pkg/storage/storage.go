@@ -2,6 +2,7 @@ package storage
 
 import (
 	"errors"
+	"strings"
 	"time"
 
 	"github.com/perryrh0dan/passline/pkg/config"
@@ -20,6 +21,15 @@ type Storage interface {
 	SetData(context.Context, Data) error
 }
 
+// Syncer is implemented by storages that synchronize with a remote
+type Syncer interface {
+	Sync(context.Context) error
+}
+
+// PasswordPrompt asks for the global password. Backends that need it to open
+// the storage itself call it on first access.
+var PasswordPrompt func() []byte
+
 // Data structure
 type Data struct {
 	Items []Item `json:"items"`
@@ -31,9 +41,18 @@ type Backup struct {
 	Items []Item    `json:"items"`
 }
 
+// BackupFile describes a backup on disk
+type BackupFile struct {
+	Path string
+	Date time.Time
+}
+
 // Item structure
 type Item struct {
 	Name        string       `json:"name"`
+	Folder      string       `json:"folder,omitempty"`
+	Tags        []string     `json:"tags,omitempty"`
+	URIs        []URI        `json:"uris,omitempty"`
 	Credentials []Credential `json:"credentials"`
 }
 
@@ -44,6 +63,69 @@ func (a ByName) Len() int           { return len(a) }
 func (a ByName) Less(i, j int) bool { return a[i].Name < a[j].Name }
 func (a ByName) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
 
+// HasTag reports whether the item carries the tag, ignoring case
+func (item *Item) HasTag(tag string) bool {
+	for _, t := range item.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// InFolder reports whether the item is in the folder or one of its sub folders
+func (item *Item) InFolder(folder string) bool {
+	folder = CleanFolder(folder)
+	if folder == "" {
+		return true
+	}
+
+	return item.Folder == folder || strings.HasPrefix(item.Folder, folder+"/")
+}
+
+// CleanFolder removes surrounding and duplicate slashes from a folder path
+func CleanFolder(folder string) string {
+	parts := []string{}
+	for _, part := range strings.Split(folder, "/") {
+		part = strings.TrimSpace(part)
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+
+	return strings.Join(parts, "/")
+}
+
+// CleanTags trims the tags and removes empty and duplicate ones
+func CleanTags(tags []string) []string {
+	cleaned := []string{}
+	seen := map[string]bool{}
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[strings.ToLower(tag)] {
+			continue
+		}
+
+		seen[strings.ToLower(tag)] = true
+		cleaned = append(cleaned, tag)
+	}
+
+	return cleaned
+}
+
+// ByFolder sorts items by folder and name, items without folder first
+type ByFolder []Item
+
+func (a ByFolder) Len() int { return len(a) }
+func (a ByFolder) Less(i, j int) bool {
+	if a[i].Folder != a[j].Folder {
+		return a[i].Folder < a[j].Folder
+	}
+	return a[i].Name < a[j].Name
+}
+func (a ByFolder) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+
 func (item *Item) GetCredentialByUsername(username string) (Credential, error) {
 	for i := 0; i < len(item.Credentials); i++ {
 		if item.Credentials[i].Username == username {
@@ -74,9 +156,32 @@ func (item *Item) GetUsernameArray() []string {
 }
 
 type Credential struct {
-	Username      string   `json:"username"`
-	Password      string   `json:"password"`
-	RecoveryCodes []string `json:"recoveryCodes"`
+	Username      string    `json:"username"`
+	Password      string    `json:"password"`
+	RecoveryCodes []string  `json:"recoveryCodes"`
+	Created       time.Time `json:"created"`
+	Modified      time.Time `json:"modified"`
+}
+
+// Now is the time stored in credentials. It is rounded to seconds so it
+// survives every storage unchanged.
+func Now() time.Time {
+	return time.Now().UTC().Truncate(time.Second)
+}
+
+// PasswordAge is the time since the password was last changed, it is zero if
+// the credential has no timestamps
+func (credential *Credential) PasswordAge(now time.Time) time.Duration {
+	changed := credential.Modified
+	if changed.IsZero() {
+		changed = credential.Created
+	}
+
+	if changed.IsZero() {
+		return 0
+	}
+
+	return now.Sub(changed)
 }
 
 func getMainDir() (string, error) {
@@ -0,0 +1,199 @@
+package storage
+
+import (
+	"errors"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// Match rules of an URI
+const (
+	MatchDomain = "domain"
+	MatchHost   = "host"
+	MatchExact  = "exact"
+	MatchRegex  = "regex"
+)
+
+// MatchRules are all rules an URI can be matched with
+var MatchRules = []string{MatchDomain, MatchHost, MatchExact, MatchRegex}
+
+// URI is an address an item is used for. Domain matches any url with the
+// same base domain, host requires the same host and port, exact the same url
+// and regex matches the url against a regular expression.
+type URI struct {
+	URI   string `json:"uri"`
+	Match string `json:"match,omitempty"`
+}
+
+// ParseURI reads an URI written as [rule:]uri, without rule the base domain
+// is matched
+func ParseURI(s string) (URI, error) {
+	s = strings.TrimSpace(s)
+	for _, rule := range MatchRules {
+		if strings.HasPrefix(s, rule+":") {
+			uri := URI{URI: strings.TrimPrefix(s, rule+":"), Match: rule}
+			if rule == MatchDomain {
+				uri.Match = ""
+			}
+			return uri, uri.validate()
+		}
+	}
+
+	uri := URI{URI: s}
+	return uri, uri.validate()
+}
+
+// ParseURIs reads a list of URIs, empty values are skipped
+func ParseURIs(values []string) ([]URI, error) {
+	uris := []URI{}
+	for _, value := range values {
+		if strings.TrimSpace(value) == "" {
+			continue
+		}
+
+		uri, err := ParseURI(value)
+		if err != nil {
+			return nil, err
+		}
+		uris = append(uris, uri)
+	}
+
+	return uris, nil
+}
+
+// FormatURIs joins the URIs with commas in the form read by ParseURI
+func FormatURIs(uris []URI) string {
+	values := []string{}
+	for _, uri := range uris {
+		values = append(values, uri.String())
+	}
+
+	return strings.Join(values, ",")
+}
+
+// String is the URI in the form read by ParseURI
+func (uri URI) String() string {
+	if uri.Match == "" || uri.Match == MatchDomain {
+		return uri.URI
+	}
+
+	return uri.Match + ":" + uri.URI
+}
+
+func (uri URI) validate() error {
+	if uri.URI == "" {
+		return errors.New("Empty url")
+	}
+
+	if uri.Match == MatchRegex {
+		_, err := regexp.Compile(uri.URI)
+		return err
+	}
+
+	if host(uri.URI) == "" {
+		return errors.New("Invalid url: " + uri.URI)
+	}
+
+	return nil
+}
+
+// Matches reports whether the url is matched by the URI
+func (uri URI) Matches(rawURL string) bool {
+	rawURL = strings.TrimSpace(rawURL)
+
+	switch uri.Match {
+	case MatchRegex:
+		re, err := regexp.Compile(uri.URI)
+		return err == nil && re.MatchString(rawURL)
+	case MatchExact:
+		return normalizeURL(uri.URI) == normalizeURL(rawURL)
+	case MatchHost:
+		h := hostPort(uri.URI)
+		return h != "" && h == hostPort(rawURL)
+	default:
+		d := baseDomain(uri.URI)
+		return d != "" && d == baseDomain(rawURL)
+	}
+}
+
+// MatchesURL reports whether one of the URIs of the item matches the url.
+// Items without URIs are matched by the base domain of their name.
+func (item *Item) MatchesURL(rawURL string) bool {
+	if len(item.URIs) == 0 {
+		return URI{URI: item.Name}.Matches(rawURL)
+	}
+
+	for _, uri := range item.URIs {
+		if uri.Matches(rawURL) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func parseURL(rawURL string) *url.URL {
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "https://" + rawURL
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil
+	}
+
+	return u
+}
+
+func normalizeURL(rawURL string) string {
+	u := parseURL(strings.TrimSpace(rawURL))
+	if u == nil {
+		return rawURL
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	if u.Path == "" {
+		u.Path = "/"
+	}
+
+	return u.String()
+}
+
+func host(rawURL string) string {
+	u := parseURL(rawURL)
+	if u == nil {
+		return ""
+	}
+
+	return strings.ToLower(u.Hostname())
+}
+
+func hostPort(rawURL string) string {
+	u := parseURL(rawURL)
+	if u == nil {
+		return ""
+	}
+
+	return strings.ToLower(u.Host)
+}
+
+// baseDomain is the registrable domain of the url host, like google.com for
+// mail.google.com. Hosts without public suffix like localhost or ip addresses
+// are returned as they are.
+func baseDomain(rawURL string) string {
+	h := host(rawURL)
+	if h == "" {
+		return ""
+	}
+
+	domain, err := publicsuffix.EffectiveTLDPlusOne(h)
+	if err != nil {
+		return h
+	}
+
+	return domain
+}
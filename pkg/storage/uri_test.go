@@ -0,0 +1,51 @@
+package storage
+
+import "testing"
+
+func TestURIMatches(t *testing.T) {
+	tests := []struct {
+		uri     string
+		url     string
+		matches bool
+	}{
+		{"google.com", "https://accounts.google.com/signin", true},
+		{"https://mail.google.com", "google.com", true},
+		{"google.com", "https://google.co.uk", false},
+		{"github.io", "https://perry.github.io", false},
+		{"host:accounts.google.com", "https://accounts.google.com/signin", true},
+		{"host:accounts.google.com", "https://mail.google.com", false},
+		{"host:localhost:8080", "http://localhost:8080/login", true},
+		{"host:localhost:8080", "http://localhost:9090", false},
+		{"exact:https://example.com/login", "https://EXAMPLE.com/login", true},
+		{"exact:https://example.com/login", "https://example.com/login?next=1", false},
+		{`regex:^https://[a-z]+\.example\.com/`, "https://shop.example.com/cart", true},
+		{`regex:^https://[a-z]+\.example\.com/`, "http://shop.example.com/cart", false},
+	}
+
+	for _, test := range tests {
+		uri, err := ParseURI(test.uri)
+		if err != nil {
+			t.Fatalf("ParseURI(%q) error: %v", test.uri, err)
+		}
+
+		if got := uri.Matches(test.url); got != test.matches {
+			t.Errorf("%q.Matches(%q) = %v; wanted %v", test.uri, test.url, got, test.matches)
+		}
+	}
+}
+
+func TestItemMatchesURL(t *testing.T) {
+	item := Item{Name: "github.com"}
+	if !item.MatchesURL("https://github.com/login") {
+		t.Errorf("MatchesURL() of an item without uris does not match its name")
+	}
+
+	item.URIs = []URI{{URI: "gitlab.com"}}
+	if item.MatchesURL("https://github.com/login") || !item.MatchesURL("gitlab.com") {
+		t.Errorf("MatchesURL() does not use the uris of the item")
+	}
+
+	if _, err := ParseURI("regex:("); err == nil {
+		t.Errorf("ParseURI() with invalid regular expression succeeded")
+	}
+}
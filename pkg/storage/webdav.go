@@ -0,0 +1,192 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/perryrh0dan/passline/pkg/config"
+)
+
+// WebDAV stores the encrypted vault as one file on a WebDAV share like
+// Nextcloud. The last known version is cached locally, so reading still
+// works without a connection.
+type WebDAV struct {
+	blobStorage
+}
+
+type webdavClient struct {
+	url       string
+	username  string
+	password  string
+	cacheFile string
+	client    *http.Client
+}
+
+// webdavCache is the local copy of the vault
+type webdavCache struct {
+	ETag string          `json:"etag"`
+	Data json.RawMessage `json:"data"`
+}
+
+func init() {
+	Register(Backend{
+		Name:        "webdav",
+		Description: "File on a WebDAV server",
+		Settings:    []string{"WebDAV.URL", "WebDAV.Username", "WebDAV.Password"},
+		Remote:      true,
+		New: func(_ context.Context) (Storage, error) {
+			return NewWebDAV()
+		},
+	})
+}
+
+func NewWebDAV() (*WebDAV, error) {
+	cfg, err := config.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	storageDir := path.Join(cfg.Directory, "storage")
+	ensureDirectories(storageDir, "")
+
+	return newWebDAV(cfg.WebDAV, path.Join(storageDir, "webdav-cache.json"))
+}
+
+func newWebDAV(cfg config.WebDAV, cacheFile string) (*WebDAV, error) {
+	if cfg.URL == "" {
+		return nil, errors.New("WebDAV url is required")
+	}
+
+	client := &webdavClient{
+		url:       cfg.URL,
+		username:  cfg.Username,
+		password:  cfg.Password,
+		cacheFile: cacheFile,
+		client:    &http.Client{Timeout: 30 * time.Second},
+	}
+
+	return &WebDAV{blobStorage{store: client}}, nil
+}
+
+func (c *webdavClient) get(ctx context.Context) ([]byte, string, error) {
+	cache, cacheErr := c.readCache()
+
+	header := http.Header{}
+	if cacheErr == nil && cache.ETag != "" {
+		header.Set("If-None-Match", cache.ETag)
+	}
+
+	res, err := c.do(ctx, http.MethodGet, nil, header)
+	if err != nil {
+		// Offline, use the local copy
+		if cacheErr == nil {
+			return cache.Data, cache.ETag, nil
+		}
+		return nil, "", err
+	}
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, "", err
+	}
+
+	switch res.StatusCode {
+	case http.StatusOK:
+		etag := res.Header.Get("ETag")
+		c.writeCache(body, etag)
+		return body, etag, nil
+	case http.StatusNotModified:
+		return cache.Data, cache.ETag, nil
+	case http.StatusNotFound:
+		return nil, "", errBlobNotFound
+	}
+
+	return nil, "", webdavError(res, body)
+}
+
+func (c *webdavClient) put(ctx context.Context, data []byte, etag string) error {
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	if etag == "" {
+		header.Set("If-None-Match", "*")
+	} else {
+		header.Set("If-Match", etag)
+	}
+
+	res, err := c.do(ctx, http.MethodPut, data, header)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	body, _ := ioutil.ReadAll(res.Body)
+
+	switch res.StatusCode {
+	case http.StatusOK, http.StatusCreated, http.StatusNoContent:
+		// Not every server returns the new etag
+		c.writeCache(data, res.Header.Get("ETag"))
+		return nil
+	case http.StatusPreconditionFailed:
+		return errPreconditionFailed
+	case http.StatusConflict:
+		return errors.New("WebDAV directory of the vault does not exist")
+	}
+
+	return webdavError(res, body)
+}
+
+func (c *webdavClient) do(ctx context.Context, method string, body []byte, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequest(method, c.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+
+	for key, values := range header {
+		req.Header[key] = values
+	}
+
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	return c.client.Do(req)
+}
+
+func (c *webdavClient) readCache() (webdavCache, error) {
+	cache := webdavCache{}
+
+	file, err := ioutil.ReadFile(c.cacheFile)
+	if err != nil {
+		return cache, err
+	}
+
+	err = json.Unmarshal(file, &cache)
+	return cache, err
+}
+
+func (c *webdavClient) writeCache(data []byte, etag string) {
+	if !json.Valid(data) {
+		return
+	}
+
+	file, err := json.Marshal(webdavCache{ETag: etag, Data: data})
+	if err != nil {
+		return
+	}
+
+	_ = ioutil.WriteFile(c.cacheFile, file, 0600)
+}
+
+func webdavError(res *http.Response, body []byte) error {
+	return fmt.Errorf("WebDAV request failed with status %s: %s", res.Status, strings.TrimSpace(string(body)))
+}
@@ -0,0 +1,166 @@
+package storage
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/perryrh0dan/passline/pkg/config"
+)
+
+// fakeWebDAV is an in-process WebDAV server for a single file with etag support
+type fakeWebDAV struct {
+	mu   sync.Mutex
+	data []byte
+	etag string
+	// notModified counts the conditional gets answered without body
+	notModified int
+	// beforePut is called once before the next put is handled
+	beforePut func()
+}
+
+func (f *fakeWebDAV) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if username, password, ok := r.BasicAuth(); !ok || username != "perry" || password != "secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if r.Method == http.MethodPut && f.beforePut != nil {
+		hook := f.beforePut
+		f.beforePut = nil
+		hook()
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch r.Method {
+	case http.MethodGet:
+		if f.data == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Header.Get("If-None-Match") == f.etag {
+			f.notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", f.etag)
+		w.Write(f.data)
+	case http.MethodPut:
+		if (r.Header.Get("If-None-Match") == "*" && f.data != nil) ||
+			(r.Header.Get("If-Match") != "" && r.Header.Get("If-Match") != f.etag) {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+
+		created := f.data == nil
+		f.data, _ = ioutil.ReadAll(r.Body)
+		sum := md5.Sum(f.data)
+		f.etag = `"` + hex.EncodeToString(sum[:]) + `"`
+		w.Header().Set("ETag", f.etag)
+		if created {
+			w.WriteHeader(http.StatusCreated)
+		} else {
+			w.WriteHeader(http.StatusNoContent)
+		}
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func TestWebDAV(t *testing.T) {
+	ctx := context.Background()
+
+	dir, err := ioutil.TempDir("", "passline-webdav")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fake := &fakeWebDAV{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	cfg := config.WebDAV{URL: server.URL + "/passline.json", Username: "perry", Password: "secret"}
+	a, _ := newWebDAV(cfg, filepath.Join(dir, "a.json"))
+	b, _ := newWebDAV(cfg, filepath.Join(dir, "b.json"))
+
+	// The file does not exist before the first write
+	items, err := a.GetAllItems(ctx)
+	if err != nil || len(items) != 0 {
+		t.Fatalf("GetAllItems() on first use = %v, %v; wanted no items", items, err)
+	}
+
+	credential := Credential{Username: "perry", Password: "encrypted", RecoveryCodes: []string{}}
+	err = a.CreateItem(ctx, Item{Name: "github.com", Credentials: []Credential{credential}})
+	if err != nil {
+		t.Fatalf("CreateItem() error: %v", err)
+	}
+
+	item, err := b.GetItemByName(ctx, "github.com")
+	if err != nil || len(item.Credentials) != 1 {
+		t.Fatalf("GetItemByName() = %v, %v; wanted the created item", item, err)
+	}
+
+	// An unchanged file is not downloaded again
+	_, _ = b.GetAllItems(ctx)
+	if fake.notModified != 1 {
+		t.Errorf("GetAllItems() of an unchanged file was answered %d times with 304; wanted 1", fake.notModified)
+	}
+
+	// A write with an outdated etag is rejected
+	_, etag, _ := b.store.get(ctx)
+	err = a.CreateItem(ctx, Item{Name: "gitlab.com", Credentials: []Credential{credential}})
+	if err != nil {
+		t.Fatalf("CreateItem() error: %v", err)
+	}
+	if err := b.store.put(ctx, []byte(`{"items":[]}`), etag); err != errPreconditionFailed {
+		t.Errorf("put() with outdated etag = %v; wanted errPreconditionFailed", err)
+	}
+	if err := b.store.put(ctx, []byte(`{"items":[]}`), ""); err != errPreconditionFailed {
+		t.Errorf("put() of a new file over an existing one = %v; wanted errPreconditionFailed", err)
+	}
+
+	// Another device writes between read and write of a, the change is
+	// applied again to the new version
+	fake.beforePut = func() {
+		b.CreateItem(ctx, Item{Name: "twitter.com", Credentials: []Credential{credential}})
+	}
+
+	err = a.UpdateItem(ctx, Item{Name: "github.com", Folder: "dev", Credentials: []Credential{credential}})
+	if err != nil {
+		t.Fatalf("UpdateItem() with concurrent write error: %v", err)
+	}
+
+	items, _ = a.GetAllItems(ctx)
+	if len(items) != 3 || items[0].Folder != "dev" {
+		t.Errorf("GetAllItems() = %v; wanted 3 items with the update", items)
+	}
+
+	// Without connection the cached copy is read
+	server.Close()
+	items, err = a.GetAllItems(ctx)
+	if err != nil || len(items) != 3 {
+		t.Errorf("GetAllItems() offline = %d items, %v; wanted 3 from the cache", len(items), err)
+	}
+}
+
+func TestWebDAVUnauthorized(t *testing.T) {
+	ctx := context.Background()
+
+	server := httptest.NewServer(&fakeWebDAV{})
+	defer server.Close()
+
+	client, _ := newWebDAV(config.WebDAV{URL: server.URL, Username: "perry", Password: "wrong"}, filepath.Join(os.TempDir(), "passline-webdav-missing.json"))
+	if _, err := client.GetAllItems(ctx); err == nil {
+		t.Errorf("GetAllItems() with wrong password succeeded")
+	}
+}
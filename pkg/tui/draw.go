@@ -0,0 +1,191 @@
+package tui
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/nsf/termbox-go"
+)
+
+const help = "/ search  u username  p password  r reveal  e edit  g generate  d delete  q quit"
+
+// listHeight is the number of visible items
+func (a *App) listHeight() int {
+	_, h := termbox.Size()
+	if h < 4 {
+		return 1
+	}
+
+	return h - 3
+}
+
+func (a *App) draw() {
+	termbox.Clear(termbox.ColorDefault, termbox.ColorDefault)
+	termbox.HideCursor()
+
+	w, h := termbox.Size()
+	listWidth := w / 3
+	if listWidth < 20 {
+		listWidth = w / 2
+	}
+
+	a.drawSearch(w)
+	a.drawList(listWidth, h)
+
+	for y := 1; y < h-1; y++ {
+		termbox.SetCell(listWidth, y, '│', termbox.ColorDefault, termbox.ColorDefault)
+	}
+	a.drawDetails(listWidth+2, w)
+
+	a.drawStatus(w, h)
+	termbox.Flush()
+}
+
+func (a *App) drawSearch(w int) {
+	fg := termbox.ColorDefault
+	if a.searching {
+		fg = termbox.ColorGreen | termbox.AttrBold
+	}
+
+	x := write(0, 0, w, fg, "Search: ")
+	x = write(x, 0, w, termbox.ColorDefault, string(a.query))
+	if a.searching {
+		termbox.SetCursor(x, 0)
+	}
+
+	count := " " + strconv.Itoa(len(a.items)) + "/" + strconv.Itoa(len(a.all))
+	write(w-len(count)-1, 0, w, termbox.ColorBlue, count)
+}
+
+func (a *App) drawList(width, h int) {
+	height := a.listHeight()
+	if a.cursor < a.offset {
+		a.offset = a.cursor
+	}
+	if a.cursor >= a.offset+height {
+		a.offset = a.cursor - height + 1
+	}
+
+	for i := a.offset; i < len(a.items) && i < a.offset+height; i++ {
+		item := a.items[i]
+		y := i - a.offset + 1
+
+		fg, bg := termbox.ColorDefault, termbox.ColorDefault
+		if i == a.cursor {
+			fg, bg = termbox.ColorBlack, termbox.ColorGreen
+			for x := 0; x < width; x++ {
+				termbox.SetCell(x, y, ' ', fg, bg)
+			}
+		}
+
+		x := 1
+		if item.Folder != "" {
+			folderFg := termbox.ColorBlue
+			if i == a.cursor {
+				folderFg = fg
+			}
+			x = writeBg(x, y, width, folderFg, bg, item.Folder+"/")
+		}
+		writeBg(x, y, width, fg, bg, item.Name)
+	}
+
+	if len(a.all) == 0 {
+		write(1, 1, width, termbox.ColorYellow, "No items yet")
+	} else if len(a.items) == 0 {
+		write(1, 1, width, termbox.ColorYellow, "No items match the filter")
+	}
+}
+
+func (a *App) drawDetails(x0, w int) {
+	item, ok := a.selected()
+	if !ok {
+		return
+	}
+
+	y := 1
+	label := func(name, value string) {
+		write(x0, y, w, termbox.ColorBlue, name)
+		write(x0+12, y, w, termbox.ColorDefault, value)
+		y++
+	}
+
+	label("Name", item.Name)
+	for i, uri := range item.URIs {
+		name := ""
+		if i == 0 {
+			name = "Urls"
+		}
+		label(name, uri.String())
+	}
+	if item.Folder != "" {
+		label("Folder", item.Folder)
+	}
+	if len(item.Tags) > 0 {
+		label("Tags", strings.Join(item.Tags, ", "))
+	}
+
+	y++
+	write(x0, y, w, termbox.AttrBold, "Credentials")
+	y++
+
+	for i, credential := range item.Credentials {
+		fg := termbox.ColorDefault
+		marker := "  "
+		if i == a.credential {
+			fg = termbox.ColorGreen | termbox.AttrBold
+			marker = "> "
+		}
+
+		x := write(x0, y, w, fg, marker+credential.Username)
+		password := "********"
+		if i == a.credential && a.revealed != nil {
+			password = a.revealed.Password
+		}
+		if x < x0+24 {
+			x = x0 + 24
+		}
+		write(x+1, y, w, fg, password)
+		y++
+	}
+
+	if a.revealed != nil && len(a.revealed.RecoveryCodes) > 0 {
+		y++
+		write(x0, y, w, termbox.ColorBlue, "Recovery codes")
+		y++
+		for _, code := range a.revealed.RecoveryCodes {
+			write(x0+2, y, w, termbox.ColorDefault, code)
+			y++
+		}
+	}
+}
+
+func (a *App) drawStatus(w, h int) {
+	if a.status == "" {
+		write(0, h-1, w, termbox.ColorDefault, help)
+		return
+	}
+
+	fg := termbox.ColorGreen
+	if a.failed {
+		fg = termbox.ColorRed
+	}
+	write(0, h-1, w, fg, a.status)
+}
+
+// write puts text at x, y and clips it at max, it returns the column after
+// the text
+func write(x, y, max int, fg termbox.Attribute, text string) int {
+	return writeBg(x, y, max, fg, termbox.ColorDefault, text)
+}
+
+func writeBg(x, y, max int, fg, bg termbox.Attribute, text string) int {
+	for _, r := range text {
+		if x >= max {
+			break
+		}
+		termbox.SetCell(x, y, r, fg, bg)
+		x++
+	}
+
+	return x
+}
@@ -0,0 +1,77 @@
+package tui
+
+import (
+	"strings"
+
+	"github.com/nsf/termbox-go"
+)
+
+// field is an input of a form
+type field struct {
+	label  string
+	value  string
+	masked bool
+}
+
+// form asks for the fields one after another, it returns false if the user
+// canceled one of them
+func (a *App) form(fields []field) ([]string, bool) {
+	values := []string{}
+	for _, f := range fields {
+		value, ok := a.input(f.label, f.value, f.masked)
+		if !ok {
+			return nil, false
+		}
+		values = append(values, value)
+	}
+
+	return values, true
+}
+
+// input asks for a value in the status line, Enter accepts and Esc cancels
+func (a *App) input(label, value string, masked bool) (string, bool) {
+	text := []rune(value)
+
+	for {
+		a.draw()
+
+		w, h := termbox.Size()
+		for x := 0; x < w; x++ {
+			termbox.SetCell(x, h-1, ' ', termbox.ColorDefault, termbox.ColorDefault)
+		}
+
+		shown := string(text)
+		if masked {
+			shown = strings.Repeat("*", len(text))
+		}
+
+		x := write(0, h-1, w, termbox.ColorGreen|termbox.AttrBold, label)
+		x = write(x, h-1, w, termbox.ColorDefault, shown)
+		termbox.SetCursor(x, h-1)
+		termbox.Flush()
+
+		ev := termbox.PollEvent()
+		if ev.Type != termbox.EventKey {
+			continue
+		}
+
+		switch ev.Key {
+		case termbox.KeyEnter:
+			return string(text), true
+		case termbox.KeyEsc, termbox.KeyCtrlC:
+			return "", false
+		case termbox.KeyCtrlU:
+			text = nil
+		case termbox.KeyBackspace, termbox.KeyBackspace2:
+			if len(text) > 0 {
+				text = text[:len(text)-1]
+			}
+		case termbox.KeySpace:
+			text = append(text, ' ')
+		default:
+			if ev.Ch != 0 {
+				text = append(text, ev.Ch)
+			}
+		}
+	}
+}
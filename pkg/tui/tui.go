@@ -0,0 +1,473 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/atotto/clipboard"
+	"github.com/nsf/termbox-go"
+
+	"github.com/perryrh0dan/passline/pkg/core"
+	"github.com/perryrh0dan/passline/pkg/storage"
+	"github.com/perryrh0dan/passline/pkg/util"
+)
+
+// App is the state of the full screen interface
+type App struct {
+	ctx  context.Context
+	core *core.Core
+
+	all       []storage.Item
+	items     []storage.Item
+	query     []rune
+	searching bool
+
+	cursor     int
+	offset     int
+	credential int
+	revealed   *storage.Credential
+
+	password []byte
+	key      []byte
+
+	status string
+	failed bool
+}
+
+// Run shows the interface until the user quits
+func Run(ctx context.Context, c *core.Core) error {
+	err := termbox.Init()
+	if err != nil {
+		return err
+	}
+	defer termbox.Close()
+
+	app := &App{ctx: ctx, core: c}
+
+	// Backends that need the password to open the storage ask for it in the
+	// interface, it is used to unlock the vault as well
+	storage.PasswordPrompt = func() []byte {
+		if app.password == nil {
+			input, _ := app.input("Global password: ", "", true)
+			app.password = []byte(input)
+		}
+		return app.password
+	}
+
+	err = app.reload()
+	if err != nil {
+		return err
+	}
+
+	return app.loop()
+}
+
+func (a *App) loop() error {
+	for {
+		a.draw()
+
+		ev := termbox.PollEvent()
+		switch ev.Type {
+		case termbox.EventError:
+			return ev.Err
+		case termbox.EventKey:
+			a.status = ""
+			a.failed = false
+
+			if a.searching {
+				a.searchKey(ev)
+				continue
+			}
+
+			if ev.Key == termbox.KeyEsc || ev.Key == termbox.KeyCtrlC || ev.Ch == 'q' {
+				return nil
+			}
+			a.listKey(ev)
+		}
+	}
+}
+
+// listKey handles a key press while the item list is focused
+func (a *App) listKey(ev termbox.Event) {
+	switch {
+	case ev.Key == termbox.KeyArrowUp || ev.Key == termbox.KeyCtrlP || ev.Ch == 'k':
+		a.move(-1)
+	case ev.Key == termbox.KeyArrowDown || ev.Key == termbox.KeyCtrlN || ev.Ch == 'j':
+		a.move(1)
+	case ev.Key == termbox.KeyPgup:
+		a.move(-a.listHeight())
+	case ev.Key == termbox.KeyPgdn:
+		a.move(a.listHeight())
+	case ev.Key == termbox.KeyHome:
+		a.move(-len(a.items))
+	case ev.Key == termbox.KeyEnd:
+		a.move(len(a.items))
+	case ev.Key == termbox.KeyArrowLeft || ev.Ch == 'h':
+		a.selectCredential(-1)
+	case ev.Key == termbox.KeyArrowRight || ev.Key == termbox.KeyTab || ev.Ch == 'l':
+		a.selectCredential(1)
+	case ev.Ch == '/':
+		a.searching = true
+	case ev.Ch == 'u':
+		a.copyUsername()
+	case ev.Ch == 'p':
+		a.copyPassword()
+	case ev.Ch == 'r':
+		a.reveal()
+	case ev.Ch == 'e':
+		a.edit()
+	case ev.Ch == 'g':
+		a.generate()
+	case ev.Ch == 'd':
+		a.delete()
+	}
+}
+
+// searchKey handles a key press while the search bar is focused
+func (a *App) searchKey(ev termbox.Event) {
+	switch ev.Key {
+	case termbox.KeyEnter:
+		a.searching = false
+	case termbox.KeyEsc, termbox.KeyCtrlC:
+		a.searching = false
+		a.query = nil
+		a.filter()
+	case termbox.KeyArrowUp:
+		a.move(-1)
+	case termbox.KeyArrowDown:
+		a.move(1)
+	case termbox.KeyBackspace, termbox.KeyBackspace2:
+		if len(a.query) > 0 {
+			a.query = a.query[:len(a.query)-1]
+			a.filter()
+		}
+	case termbox.KeySpace:
+		a.query = append(a.query, ' ')
+		a.filter()
+	default:
+		if ev.Ch != 0 {
+			a.query = append(a.query, ev.Ch)
+			a.filter()
+		}
+	}
+}
+
+// reload reads all items from the storage
+func (a *App) reload() error {
+	items, err := a.core.GetSites(a.ctx)
+	if err != nil {
+		return err
+	}
+
+	sort.Sort(storage.ByFolder(items))
+	a.all = items
+	a.filter()
+	return nil
+}
+
+// filter shows the items matching the query, the best match first
+func (a *App) filter() {
+	selected := ""
+	if item, ok := a.selected(); ok {
+		selected = item.Name
+	}
+
+	if len(a.query) == 0 {
+		a.items = a.all
+	} else {
+		a.items = []storage.Item{}
+		for _, result := range core.SearchItems(a.all, string(a.query)) {
+			a.items = append(a.items, result.Item)
+		}
+	}
+
+	a.cursor = 0
+	a.offset = 0
+	for i, item := range a.items {
+		if item.Name == selected && len(a.query) == 0 {
+			a.cursor = i
+		}
+	}
+	a.credential = 0
+	a.revealed = nil
+}
+
+func (a *App) move(n int) {
+	a.cursor += n
+	if a.cursor >= len(a.items) {
+		a.cursor = len(a.items) - 1
+	}
+	if a.cursor < 0 {
+		a.cursor = 0
+	}
+
+	a.credential = 0
+	a.revealed = nil
+}
+
+func (a *App) selectCredential(n int) {
+	item, ok := a.selected()
+	if !ok || len(item.Credentials) == 0 {
+		return
+	}
+
+	a.credential = (a.credential + n + len(item.Credentials)) % len(item.Credentials)
+	a.revealed = nil
+}
+
+func (a *App) selected() (storage.Item, bool) {
+	if a.cursor < 0 || a.cursor >= len(a.items) {
+		return storage.Item{}, false
+	}
+
+	return a.items[a.cursor], true
+}
+
+func (a *App) selectedCredential() (storage.Item, storage.Credential, bool) {
+	item, ok := a.selected()
+	if !ok || a.credential >= len(item.Credentials) {
+		return storage.Item{}, storage.Credential{}, false
+	}
+
+	return item, item.Credentials[a.credential], true
+}
+
+// unlock asks for the global password once and keeps the vault key
+func (a *App) unlock() bool {
+	if a.key != nil {
+		return true
+	}
+
+	password := a.password
+	if password == nil && a.core.PasswordRequired() {
+		input, ok := a.input("Global password: ", "", true)
+		if !ok {
+			return false
+		}
+		password = []byte(input)
+	}
+
+	key, err := a.core.UnlockKey(a.ctx, password)
+	if err != nil {
+		a.fail(err)
+		return false
+	}
+
+	valid, err := a.core.CheckPassword(a.ctx, key)
+	// CheckPassword prints to the terminal if the password is wrong
+	termbox.Sync()
+	if err != nil || !valid {
+		a.fail(errors.New("Invalid password"))
+		return false
+	}
+
+	a.password = password
+	a.key = key
+	return true
+}
+
+// decrypted returns the selected credential with decrypted secrets
+func (a *App) decrypted() (storage.Item, storage.Credential, bool) {
+	item, credential, ok := a.selectedCredential()
+	if !ok || !a.unlock() {
+		return storage.Item{}, storage.Credential{}, false
+	}
+
+	err := a.core.DecryptCredential(&credential, a.key)
+	if err != nil {
+		a.fail(err)
+		return storage.Item{}, storage.Credential{}, false
+	}
+
+	return item, credential, true
+}
+
+func (a *App) copyUsername() {
+	item, credential, ok := a.selectedCredential()
+	if !ok {
+		return
+	}
+
+	err := clipboard.WriteAll(credential.Username)
+	if err != nil {
+		a.fail(errors.New("Error occured while copying to clipboard"))
+		return
+	}
+
+	a.info("Copied username of " + item.Name)
+}
+
+func (a *App) copyPassword() {
+	item, credential, ok := a.decrypted()
+	if !ok {
+		return
+	}
+
+	err := clipboard.WriteAll(credential.Password)
+	if err != nil {
+		a.fail(errors.New("Error occured while copying to clipboard"))
+		return
+	}
+
+	a.info("Copied password of " + item.Name + "/" + credential.Username)
+}
+
+func (a *App) reveal() {
+	if a.revealed != nil {
+		a.revealed = nil
+		return
+	}
+
+	_, credential, ok := a.decrypted()
+	if ok {
+		a.revealed = &credential
+	}
+}
+
+func (a *App) edit() {
+	item, credential, ok := a.decrypted()
+	if !ok {
+		return
+	}
+	username := credential.Username
+
+	values, ok := a.form([]field{
+		{"Username: ", credential.Username, false},
+		{"Password: ", credential.Password, true},
+		{"Recovery codes: ", util.ArrayToString(credential.RecoveryCodes), false},
+		{"Urls: ", storage.FormatURIs(item.URIs), false},
+		{"Folder: ", item.Folder, false},
+		{"Tags: ", util.ArrayToString(item.Tags), false},
+	})
+	if !ok {
+		return
+	}
+
+	credential.Username = values[0]
+	credential.Password = values[1]
+	credential.RecoveryCodes = []string{}
+	if codes := strings.TrimSpace(values[2]); codes != "" {
+		credential.RecoveryCodes = util.StringToArray(codes)
+	}
+
+	uris, err := storage.ParseURIs(util.StringToArray(values[3]))
+	if err != nil {
+		a.fail(err)
+		return
+	}
+
+	details := core.Details{}
+	if values[3] != storage.FormatURIs(item.URIs) {
+		details.URIs = uris
+	}
+	if folder := values[4]; folder != item.Folder {
+		details.Folder = &folder
+	}
+	if tags := values[5]; tags != util.ArrayToString(item.Tags) {
+		details.Tags = util.StringToArray(tags)
+	}
+
+	err = a.core.EditItem(a.ctx, item.Name, username, credential, details, a.key)
+	if err != nil {
+		a.fail(err)
+		return
+	}
+
+	a.refresh(item.Name)
+	a.info("Changed " + item.Name + "/" + credential.Username)
+}
+
+func (a *App) generate() {
+	name, ok := a.input("Name: ", string(a.query), false)
+	if !ok || name == "" {
+		return
+	}
+
+	username, ok := a.input("Username: ", "", false)
+	if !ok {
+		return
+	}
+
+	for _, item := range a.all {
+		if item.Name != name {
+			continue
+		}
+
+		if _, err := item.GetCredentialByUsername(username); err == nil {
+			a.fail(errors.New("Username already exists"))
+			return
+		}
+	}
+
+	if !a.unlock() {
+		return
+	}
+
+	credential, err := a.core.GenerateItem(a.ctx, name, username, []string{}, core.Details{}, a.key)
+	if err != nil {
+		a.fail(err)
+		return
+	}
+
+	a.query = nil
+	a.refresh(name)
+
+	err = clipboard.WriteAll(credential.Password)
+	if err != nil {
+		a.info("Generated password for " + name + "/" + username)
+		return
+	}
+	a.info("Generated password for " + name + "/" + username + " and copied it")
+}
+
+func (a *App) delete() {
+	item, credential, ok := a.selectedCredential()
+	if !ok {
+		return
+	}
+
+	answer, ok := a.input("Delete "+item.Name+"/"+credential.Username+"? [y/N] ", "", false)
+	if !ok || strings.ToLower(answer) != "y" {
+		return
+	}
+
+	err := a.core.DeleteItem(a.ctx, item.Name, credential.Username)
+	if err != nil {
+		a.fail(err)
+		return
+	}
+
+	a.refresh("")
+	a.info("Deleted " + item.Name + "/" + credential.Username)
+}
+
+// refresh reloads the items after a change and selects the named item
+func (a *App) refresh(name string) {
+	cursor := a.cursor
+
+	err := a.reload()
+	if err != nil {
+		a.fail(err)
+		return
+	}
+
+	a.cursor = cursor
+	for i, item := range a.items {
+		if item.Name == name {
+			a.cursor = i
+		}
+	}
+	a.move(0)
+}
+
+func (a *App) info(message string) {
+	a.status = message
+	a.failed = false
+}
+
+func (a *App) fail(err error) {
+	a.status = err.Error()
+	a.failed = true
+}
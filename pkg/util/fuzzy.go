@@ -0,0 +1,150 @@
+package util
+
+import (
+	"sort"
+	"unicode"
+)
+
+const (
+	scoreMatch       = 16
+	scoreConsecutive = 16
+	scoreBoundary    = 24
+	scoreCase        = 1
+	penaltyGap       = 2
+	penaltyLeading   = 1
+	maxLeading       = 12
+)
+
+// FuzzyMatch reports whether all characters of pattern appear in text in
+// order, ignoring case. The score rewards consecutive characters and matches
+// at the start of words, positions are the matched rune indices of text.
+func FuzzyMatch(pattern, text string) (int, []int, bool) {
+	p := []rune(pattern)
+	t := []rune(text)
+	if len(p) == 0 {
+		return 0, []int{}, true
+	}
+
+	best := -1
+	var bestPositions []int
+
+	// Try every start of the first character and keep the best alignment
+	for start := 0; start < len(t); start++ {
+		if !equalFold(p[0], t[start]) {
+			continue
+		}
+
+		positions := matchFrom(p, t, start)
+		if positions == nil {
+			break
+		}
+
+		score := fuzzyScore(p, t, positions)
+		if score > best {
+			best = score
+			bestPositions = positions
+		}
+	}
+
+	if bestPositions == nil {
+		return 0, nil, false
+	}
+
+	return best, bestPositions, true
+}
+
+// FuzzyFilter returns the indices of the items matching pattern, the best
+// match first. Items with the same score keep their order.
+func FuzzyFilter(items []string, pattern string) []int {
+	indices := []int{}
+	scores := map[int]int{}
+	for i, item := range items {
+		score, _, ok := FuzzyMatch(pattern, item)
+		if ok {
+			indices = append(indices, i)
+			scores[i] = score
+		}
+	}
+
+	sort.SliceStable(indices, func(a, b int) bool {
+		return scores[indices[a]] > scores[indices[b]]
+	})
+
+	return indices
+}
+
+// matchFrom matches the pattern greedily starting at start and then moves
+// every matched character as far right as possible, so the characters end up
+// next to each other
+func matchFrom(p, t []rune, start int) []int {
+	positions := make([]int, 0, len(p))
+	j := 0
+	for i := start; i < len(t) && j < len(p); i++ {
+		if equalFold(p[j], t[i]) {
+			positions = append(positions, i)
+			j++
+		}
+	}
+
+	if j < len(p) {
+		return nil
+	}
+
+	for k := len(p) - 2; k >= 1; k-- {
+		for i := positions[k+1] - 1; i > positions[k]; i-- {
+			if equalFold(p[k], t[i]) {
+				positions[k] = i
+				break
+			}
+		}
+	}
+
+	return positions
+}
+
+func fuzzyScore(p, t []rune, positions []int) int {
+	leading := positions[0]
+	if leading > maxLeading {
+		leading = maxLeading
+	}
+	score := -leading * penaltyLeading
+
+	for k, i := range positions {
+		score += scoreMatch
+		if p[k] == t[i] {
+			score += scoreCase
+		}
+
+		if isBoundary(t, i) {
+			score += scoreBoundary
+		}
+
+		if k > 0 {
+			if i == positions[k-1]+1 {
+				score += scoreConsecutive
+			} else {
+				score -= (i - positions[k-1] - 1) * penaltyGap
+			}
+		}
+	}
+
+	return score
+}
+
+// isBoundary reports whether the rune at i starts a word
+func isBoundary(t []rune, i int) bool {
+	if i == 0 {
+		return true
+	}
+
+	prev := t[i-1]
+	if !unicode.IsLetter(prev) && !unicode.IsDigit(prev) {
+		return true
+	}
+
+	return unicode.IsLower(prev) && unicode.IsUpper(t[i])
+}
+
+func equalFold(a, b rune) bool {
+	return a == b || unicode.ToLower(a) == unicode.ToLower(b)
+}
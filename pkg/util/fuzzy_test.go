@@ -0,0 +1,41 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFuzzyMatch(t *testing.T) {
+	tests := []struct {
+		pattern   string
+		text      string
+		ok        bool
+		positions []int
+	}{
+		{"git", "GitHub.com", true, []int{0, 1, 2}},
+		{"ghc", "github.com", true, []int{0, 3, 7}},
+		{"hub", "github.com", true, []int{3, 4, 5}},
+		{"bog", "github.com", false, nil},
+		{"", "github.com", true, []int{}},
+	}
+
+	for _, test := range tests {
+		_, positions, ok := FuzzyMatch(test.pattern, test.text)
+		if ok != test.ok || !reflect.DeepEqual(positions, test.positions) {
+			t.Errorf("FuzzyMatch(%q, %q) = %v, %v; wanted %v, %v", test.pattern, test.text, positions, ok, test.positions, test.ok)
+		}
+	}
+}
+
+func TestFuzzyFilter(t *testing.T) {
+	items := []string{"digitalocean.com", "login.gov", "GitHub.com", "gitlab.com"}
+
+	got := FuzzyFilter(items, "git")
+	if len(got) != 3 || items[got[0]] != "GitHub.com" && items[got[0]] != "gitlab.com" {
+		t.Errorf("FuzzyFilter() = %v; wanted matches at the start of the name first", got)
+	}
+
+	if last := items[got[len(got)-1]]; last != "digitalocean.com" {
+		t.Errorf("FuzzyFilter() ranked %s last; wanted digitalocean.com", last)
+	}
+}